@@ -1,6 +1,8 @@
 package aws
 
 import (
+	"strings"
+
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/aws/session"
@@ -81,3 +83,87 @@ func GetALBAttributes(loadBalancername string, logger log.Logger) (string, strin
 	}
 	return "", ""
 }
+
+// IsLoadBalancerDNSName returns true if the DNS name is one of a classic, application or network load balancer,
+// e.g. app-1234.eu-central-1.elb.amazonaws.com or net-1234.elb.eu-central-1.amazonaws.com
+func IsLoadBalancerDNSName(dnsName string) bool {
+	dnsName = strings.ToLower(strings.TrimSuffix(dnsName, "."))
+	return strings.Contains(dnsName, ".elb.") && (strings.HasSuffix(dnsName, ".amazonaws.com") || strings.HasSuffix(dnsName, ".amazonaws.com.cn"))
+}
+
+// GetLoadBalancerHostedZoneID returns the canonical hosted zone ID of the classic, application or network load balancer
+// with the given DNS name, or an empty string if there is none. All load balancers of the account are listed,
+// so the result should be cached.
+func GetLoadBalancerHostedZoneID(dnsName string, logger log.Logger) string {
+	sess := session.Must(session.NewSession())
+	dnsName = strings.ToLower(strings.TrimSuffix(dnsName, "."))
+
+	hostedZoneID := ""
+	err := elb.New(sess).DescribeLoadBalancersPages(&elb.DescribeLoadBalancersInput{}, func(output *elb.DescribeLoadBalancersOutput, lastPage bool) bool {
+		for _, loadBalancerDescription := range output.LoadBalancerDescriptions {
+			if strings.ToLower(*loadBalancerDescription.DNSName) == dnsName {
+				hostedZoneID = *loadBalancerDescription.CanonicalHostedZoneNameID
+				return false
+			}
+		}
+		return true
+	})
+	if err != nil {
+		level.Error(logger).Log("msg", err.Error())
+	}
+	if hostedZoneID != "" {
+		return hostedZoneID
+	}
+
+	err = elbv2.New(sess).DescribeLoadBalancersPages(&elbv2.DescribeLoadBalancersInput{}, func(output *elbv2.DescribeLoadBalancersOutput, lastPage bool) bool {
+		for _, loadBalancer := range output.LoadBalancers {
+			if strings.ToLower(*loadBalancer.DNSName) == dnsName {
+				hostedZoneID = *loadBalancer.CanonicalHostedZoneId
+				return false
+			}
+		}
+		return true
+	})
+	if err != nil {
+		level.Error(logger).Log("msg", err.Error())
+	}
+	return hostedZoneID
+}
+
+// LookupLoadBalancer returns the DNS name and canonical hosted zone ID of the classic, application or network load balancer
+// with the given name. Both are empty if there is no load balancer with the name, other errors are returned.
+func LookupLoadBalancer(loadBalancerName string) (string, string, error) {
+	sess := session.Must(session.NewSession())
+
+	output, err := elb.New(sess).DescribeLoadBalancers(&elb.DescribeLoadBalancersInput{
+		LoadBalancerNames: []*string{aws.String(loadBalancerName)},
+	})
+	if err != nil && !isErrorCode(err, elb.ErrCodeAccessPointNotFoundException) {
+		return "", "", err
+	}
+	if err == nil {
+		for _, loadBalancerDescription := range output.LoadBalancerDescriptions {
+			return *loadBalancerDescription.DNSName, *loadBalancerDescription.CanonicalHostedZoneNameID, nil
+		}
+	}
+
+	outputV2, err := elbv2.New(sess).DescribeLoadBalancers(&elbv2.DescribeLoadBalancersInput{
+		Names: []*string{aws.String(loadBalancerName)},
+	})
+	if err != nil {
+		if isErrorCode(err, elbv2.ErrCodeLoadBalancerNotFoundException) {
+			return "", "", nil
+		}
+		return "", "", err
+	}
+	for _, loadBalancer := range outputV2.LoadBalancers {
+		return *loadBalancer.DNSName, *loadBalancer.CanonicalHostedZoneId, nil
+	}
+	return "", "", nil
+}
+
+// check if err is an AWS error with the given code
+func isErrorCode(err error, code string) bool {
+	aerr, ok := err.(awserr.Error)
+	return ok && aerr.Code() == code
+}
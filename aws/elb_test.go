@@ -0,0 +1,25 @@
+package aws
+
+import "testing"
+
+func TestIsLoadBalancerDNSName(t *testing.T) {
+	tests := []struct {
+		dnsName      string
+		loadBalancer bool
+	}{
+		{"app-1234.eu-central-1.elb.amazonaws.com", true},
+		{"dualstack.app-1234.eu-central-1.elb.amazonaws.com.", true},
+		{"internal-app-1234.eu-central-1.elb.amazonaws.com", true},
+		{"net-1234.elb.eu-central-1.amazonaws.com", true},
+		{"App-1234.cn-north-1.ELB.amazonaws.com.cn", true},
+		{"d111111abcdef8.cloudfront.net", false},
+		{"bucket.s3-website.eu-central-1.amazonaws.com", false},
+		{"elb.example.com", false},
+		{"", false},
+	}
+	for _, test := range tests {
+		if loadBalancer := IsLoadBalancerDNSName(test.dnsName); loadBalancer != test.loadBalancer {
+			t.Errorf("IsLoadBalancerDNSName(%q) = %v, want %v", test.dnsName, loadBalancer, test.loadBalancer)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package aws
+
+import (
+	"github.com/aws/aws-sdk-go/service/route53"
+	"github.com/dbsystel/AmazonRoute53-ingress-controller/provider"
+)
+
+// Route53 is the provider for Amazon Route53
+type Route53 struct{}
+
+// NewProvider returns the provider for Amazon Route53
+func NewProvider() provider.Provider {
+	return Route53{}
+}
+
+// Zones returns all hosted zones
+func (Route53) Zones(roleARN string) ([]provider.Zone, error) {
+	return ListHostedZones(roleARN)
+}
+
+// RecordSets returns all record sets with the given name in the hosted zone
+func (Route53) RecordSets(zoneID, roleARN, name string) ([]*route53.ResourceRecordSet, error) {
+	return GetResourceRecordSets(zoneID, roleARN, name)
+}
+
+// ListRecordSets returns all record sets of the hosted zone
+func (Route53) ListRecordSets(zoneID, roleARN string) ([]*route53.ResourceRecordSet, error) {
+	return ListResourceRecordSets(zoneID, roleARN)
+}
+
+// ApplyChanges submits the changes as one batch to the hosted zone and returns the ID of the submitted change
+func (Route53) ApplyChanges(zoneID, roleARN string, changes []*route53.Change) (string, error) {
+	return ChangeRecordSets(zoneID, roleARN, changes)
+}
+
+// ChangeStatus returns the status (PENDING/INSYNC) of a submitted change
+func (Route53) ChangeStatus(changeID, roleARN string) (string, error) {
+	return GetChangeStatus(changeID, roleARN)
+}
+
+// CheckConnectivity verifies that the Amazon Route53 API is reachable with the current credentials
+func (Route53) CheckConnectivity() error {
+	return CheckConnectivity()
+}
+
+// AliasRecords reports that ALIAS records are supported
+func (Route53) AliasRecords() bool {
+	return true
+}
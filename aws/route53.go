@@ -1,25 +1,16 @@
 package aws
 
 import (
-	"errors"
-	"regexp"
+	"strconv"
 	"strings"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/route53"
-	"github.com/go-kit/kit/log"
-	"github.com/go-kit/kit/log/level"
+	"github.com/dbsystel/AmazonRoute53-ingress-controller/provider"
 )
 
-// define struct
-type HostedZone struct {
-	name string
-	id   string
-}
-
-//
-func constructResourceRecordSet(aliasName, aliasHostedZoneID, name string, dnsType string) (resourceRecordSet *route53.ResourceRecordSet) {
+func constructResourceRecordSet(aliasName, aliasHostedZoneID, name string, dnsType string, ttl int64) (resourceRecordSet *route53.ResourceRecordSet) {
 	if strings.ToUpper(dnsType) == "ALIAS" {
 		resourceRecordSet = &route53.ResourceRecordSet{
 			AliasTarget: &route53.AliasTarget{
@@ -37,7 +28,7 @@ func constructResourceRecordSet(aliasName, aliasHostedZoneID, name string, dnsTy
 					Value: aws.String(aliasName),
 				},
 			},
-			TTL:  aws.Int64(300),
+			TTL:  aws.Int64(ttl),
 			Name: aws.String(name),
 			Type: aws.String("CNAME"),
 		}
@@ -46,21 +37,72 @@ func constructResourceRecordSet(aliasName, aliasHostedZoneID, name string, dnsTy
 	return resourceRecordSet
 }
 
-// ChangeRecordSet modifies Amazon Route53 recordset with given state (upsert/delete)
-func ChangeRecordSet(state, aliasName, aliasHostedZoneID, name, hostedZoneID string, dnsType string) (string, error) {
-	sess := session.Must(session.NewSession())
-	svc := route53.New(sess)
+// NewChange returns a change of the record set for the given name with given state (upsert/delete)
+func NewChange(state, aliasName, aliasHostedZoneID, name string, dnsType string, ttl int64) *route53.Change {
+	return &route53.Change{
+		Action:            aws.String(state),
+		ResourceRecordSet: constructResourceRecordSet(aliasName, aliasHostedZoneID, name, dnsType, ttl),
+	}
+}
 
-	resourceRecordSet := constructResourceRecordSet(aliasName, aliasHostedZoneID, name, dnsType)
+// NewRecordSetChange returns a change of the given record set with given state (upsert/delete)
+func NewRecordSetChange(state string, resourceRecordSet *route53.ResourceRecordSet) *route53.Change {
+	return &route53.Change{
+		Action:            aws.String(state),
+		ResourceRecordSet: resourceRecordSet,
+	}
+}
 
-	input := &route53.ChangeResourceRecordSetsInput{
-		ChangeBatch: &route53.ChangeBatch{
-			Changes: []*route53.Change{
+// NewResourceRecordSet returns a record set of given type with the given values
+func NewResourceRecordSet(name, recordType string, ttl int64, values []string) *route53.ResourceRecordSet {
+	resourceRecordSet := &route53.ResourceRecordSet{
+		Name: aws.String(name),
+		Type: aws.String(recordType),
+		TTL:  aws.Int64(ttl),
+	}
+	for _, value := range values {
+		resourceRecordSet.ResourceRecords = append(resourceRecordSet.ResourceRecords, &route53.ResourceRecord{Value: aws.String(value)})
+	}
+	return resourceRecordSet
+}
+
+// NewAliasResourceRecordSet returns an alias record set of given type pointing to the given target
+func NewAliasResourceRecordSet(name, recordType, dnsName, hostedZoneID string, evaluateTargetHealth bool) *route53.ResourceRecordSet {
+	return &route53.ResourceRecordSet{
+		AliasTarget: &route53.AliasTarget{
+			DNSName:              aws.String(dnsName),
+			EvaluateTargetHealth: aws.Bool(evaluateTargetHealth),
+			HostedZoneId:         aws.String(hostedZoneID),
+		},
+		Name: aws.String(name),
+		Type: aws.String(recordType),
+	}
+}
+
+// NewTXTChange returns a change of the TXT record set for the given name with given state (upsert/delete)
+func NewTXTChange(state, name, value string, ttl int64) *route53.Change {
+	return &route53.Change{
+		Action: aws.String(state),
+		ResourceRecordSet: &route53.ResourceRecordSet{
+			ResourceRecords: []*route53.ResourceRecord{
 				{
-					Action:            aws.String(state),
-					ResourceRecordSet: resourceRecordSet,
+					Value: aws.String(strconv.Quote(value)),
 				},
 			},
+			TTL:  aws.Int64(ttl),
+			Name: aws.String(name),
+			Type: aws.String(route53.RRTypeTxt),
+		},
+	}
+}
+
+// ChangeRecordSets submits the given changes as one batch to the hosted zone and returns the ID of the submitted change
+func ChangeRecordSets(hostedZoneID, roleARN string, changes []*route53.Change) (string, error) {
+	svc := route53.New(newSession(roleARN))
+
+	input := &route53.ChangeResourceRecordSetsInput{
+		ChangeBatch: &route53.ChangeBatch{
+			Changes: changes,
 		},
 		HostedZoneId: aws.String(hostedZoneID),
 	}
@@ -70,31 +112,119 @@ func ChangeRecordSet(state, aliasName, aliasHostedZoneID, name, hostedZoneID str
 		return "", err
 	}
 
-	return result.String(), nil
+	return *result.ChangeInfo.Id, nil
 }
 
-// return Hosted Zone ID for provided host
-func GetHostedZone(host string, logger log.Logger) (string, error) {
-	host = host + "."
-	level.Debug(logger).Log("msg", "Searching Hosted Zone ID for provided host ", "host", host)
-	sess := session.Must(session.NewSession())
-	svc := route53.New(sess)
+// NormalizeName returns the record name without trailing dot and with the octal escapes used by Route53
+// replaced by their characters, e.g. \052.example.com. becomes *.example.com
+func NormalizeName(name string) string {
+	name = strings.TrimSuffix(name, ".")
+	if !strings.Contains(name, `\`) {
+		return name
+	}
+	var normalized strings.Builder
+	for i := 0; i < len(name); i++ {
+		if name[i] == '\\' && i+3 < len(name) && isOctal(name[i+1:i+4]) {
+			if code, err := strconv.ParseUint(name[i+1:i+4], 8, 8); err == nil {
+				normalized.WriteByte(byte(code))
+				i += 3
+				continue
+			}
+		}
+		normalized.WriteByte(name[i])
+	}
+	return normalized.String()
+}
+
+// check if the string consists of octal digits only
+func isOctal(digits string) bool {
+	for _, digit := range digits {
+		if digit < '0' || digit > '7' {
+			return false
+		}
+	}
+	return true
+}
 
-	input := &route53.ListHostedZonesInput{}
-	output, err := svc.ListHostedZones(input)
+// GetResourceRecordSets returns all record sets with given name
+func GetResourceRecordSets(hostedZoneID, roleARN, name string) ([]*route53.ResourceRecordSet, error) {
+	svc := route53.New(newSession(roleARN))
 
+	input := &route53.ListResourceRecordSetsInput{
+		HostedZoneId:    aws.String(hostedZoneID),
+		StartRecordName: aws.String(name),
+		MaxItems:        aws.String("20"),
+	}
+	output, err := svc.ListResourceRecordSets(input)
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+
+	var resourceRecordSets []*route53.ResourceRecordSet
+	for _, resourceRecordSet := range output.ResourceRecordSets {
+		if NormalizeName(*resourceRecordSet.Name) == NormalizeName(name) {
+			resourceRecordSets = append(resourceRecordSets, resourceRecordSet)
+		}
+	}
+	return resourceRecordSets, nil
+}
+
+// ListResourceRecordSets returns all record sets of the hosted zone
+func ListResourceRecordSets(hostedZoneID, roleARN string) ([]*route53.ResourceRecordSet, error) {
+	svc := route53.New(newSession(roleARN))
+
+	input := &route53.ListResourceRecordSetsInput{
+		HostedZoneId: aws.String(hostedZoneID),
 	}
+	var resourceRecordSets []*route53.ResourceRecordSet
+	err := svc.ListResourceRecordSetsPages(input, func(output *route53.ListResourceRecordSetsOutput, lastPage bool) bool {
+		resourceRecordSets = append(resourceRecordSets, output.ResourceRecordSets...)
+		return true
+	})
+	return resourceRecordSets, err
+}
 
-	reg := regexp.MustCompile("^/hostedzone/")
-	for _, hostedZone := range output.HostedZones {
+// GetChangeStatus returns the status (PENDING/INSYNC) of a submitted Amazon Route53 change
+func GetChangeStatus(changeID, roleARN string) (string, error) {
+	svc := route53.New(newSession(roleARN))
 
-		if strings.HasSuffix(host, "."+*hostedZone.Name) {
+	input := &route53.GetChangeInput{
+		Id: aws.String(changeID),
+	}
+	output, err := svc.GetChange(input)
+	if err != nil {
+		return "", err
+	}
 
-			return reg.ReplaceAllString(*hostedZone.Id, ""), nil
+	return *output.ChangeInfo.Status, nil
+}
+
+// ListHostedZones returns all hosted zones, listed with the given IAM role if roleARN is not empty
+func ListHostedZones(roleARN string) ([]provider.Zone, error) {
+	svc := route53.New(newSession(roleARN))
+
+	var zones []provider.Zone
+	err := svc.ListHostedZonesPages(&route53.ListHostedZonesInput{}, func(output *route53.ListHostedZonesOutput, lastPage bool) bool {
+		for _, hostedZone := range output.HostedZones {
+			zones = append(zones, provider.Zone{
+				Name: strings.TrimSuffix(*hostedZone.Name, "."),
+				ID:   strings.TrimPrefix(*hostedZone.Id, "/hostedzone/"),
+			})
 		}
+		return true
+	})
+	return zones, err
+}
+
+// CheckConnectivity verifies that the Amazon Route53 API is reachable with the current credentials
+func CheckConnectivity() error {
+	sess := session.Must(session.NewSession())
+	svc := route53.New(sess)
+
+	input := &route53.ListHostedZonesInput{
+		MaxItems: aws.String("1"),
 	}
+	_, err := svc.ListHostedZones(input)
 
-	return "", errors.New("Hosted Zone ID for provided string: " + host + " not found!")
+	return err
 }
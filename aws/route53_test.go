@@ -0,0 +1,24 @@
+package aws
+
+import "testing"
+
+func TestNormalizeName(t *testing.T) {
+	tests := []struct {
+		name       string
+		normalized string
+	}{
+		{"app.example.com.", "app.example.com"},
+		{"app.example.com", "app.example.com"},
+		{`\052.example.com.`, "*.example.com"},
+		{`a\137b.example.com.`, "a_b.example.com"},
+		{`\052`, "*"},
+		{`a\05.example.com.`, `a\05.example.com`},
+		{`a\999.example.com.`, `a\999.example.com`},
+		{`trailing\`, `trailing\`},
+	}
+	for _, test := range tests {
+		if normalized := NormalizeName(test.name); normalized != test.normalized {
+			t.Errorf("NormalizeName(%q) = %q, want %q", test.name, normalized, test.normalized)
+		}
+	}
+}
@@ -0,0 +1,16 @@
+package aws
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials/stscreds"
+	"github.com/aws/aws-sdk-go/aws/session"
+)
+
+// return a new session, which assumes the given IAM role if roleARN is not empty
+func newSession(roleARN string) *session.Session {
+	sess := session.Must(session.NewSession())
+	if roleARN == "" {
+		return sess
+	}
+	return sess.Copy(&aws.Config{Credentials: stscreds.NewCredentials(sess, roleARN)})
+}
@@ -1,30 +1,86 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
+	"net/http"
 	"os"
 	"os/signal"
 	"path/filepath"
 	"sync"
 	"syscall"
+	"time"
 
+	"github.com/dbsystel/AmazonRoute53-ingress-controller/aws"
+	"github.com/dbsystel/AmazonRoute53-ingress-controller/config"
 	"github.com/dbsystel/AmazonRoute53-ingress-controller/controller"
-	"github.com/dbsystel/kube-controller-dbsystel-go-common/controller/ingress"
+	"github.com/dbsystel/AmazonRoute53-ingress-controller/health"
+	"github.com/dbsystel/AmazonRoute53-ingress-controller/informer"
+	"github.com/dbsystel/AmazonRoute53-ingress-controller/provider"
+	"github.com/dbsystel/AmazonRoute53-ingress-controller/rfc2136"
+	"github.com/dbsystel/AmazonRoute53-ingress-controller/zonefile"
 	"github.com/dbsystel/kube-controller-dbsystel-go-common/kubernetes"
 	k8sflag "github.com/dbsystel/kube-controller-dbsystel-go-common/kubernetes/flag"
 	opslog "github.com/dbsystel/kube-controller-dbsystel-go-common/log"
 	logflag "github.com/dbsystel/kube-controller-dbsystel-go-common/log/flag"
 	"github.com/go-kit/kit/log/level"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	kingpin "gopkg.in/alecthomas/kingpin.v2"
+	"k8s.io/client-go/dynamic"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/clientcmd"
 )
 
 var (
-	app             = kingpin.New(filepath.Base(os.Args[0]), "AmazonRoute53-ingress-controller")
-	allowlistPrefix = app.Flag("allowlist-prefix", "Allowlist prefix for route53 records").String()
-	allowlistSuffix = app.Flag("allowlist-suffix", "Allowlist suffix for route53 records").String()
-	deleteAlias     = app.Flag("delete-alias", "if true, recordset type alias will be deleted before other recordset type being created.").Bool()
-	deleteCname     = app.Flag("delete-cname", "if true, recordset type cname will be deleted before other recordset type being created.").Bool()
-	dNSType         = app.Flag("dns-type", "DNS Record Type(alias / cname)").Default("cname").String()
+	app                  = kingpin.New(filepath.Base(os.Args[0]), "AmazonRoute53-ingress-controller")
+	allowlistPrefix      = app.Flag("allowlist-prefix", "Allowlist prefix for route53 records").String()
+	allowlistSuffix      = app.Flag("allowlist-suffix", "Allowlist suffix for route53 records").String()
+	allowlistGlob        = app.Flag("allowlist-glob", "Allowlist glob patterns for route53 records, e.g. *.dev.example.com").String()
+	allowlistRegex       = app.Flag("allowlist-regex", "Allowlist regular expression for route53 records, can be repeated").Strings()
+	denylist             = app.Flag("denylist", "Denylist glob patterns for route53 records, always wins over the allowlist").String()
+	deleteAlias          = app.Flag("delete-alias", "DEPRECATED: ignored, record set types are migrated automatically").Hidden().Bool()
+	deleteCname          = app.Flag("delete-cname", "DEPRECATED: ignored, record set types are migrated automatically").Hidden().Bool()
+	dNSType              = app.Flag("dns-type", "DNS Record Type(alias / cname)").Default("cname").String()
+	ttl                  = app.Flag("ttl", "TTL of CNAME and ownership records").Default("300").Int64()
+	ownerID              = app.Flag("owner-id", "if set, an ownership TXT record with this ID is written for every created record").String()
+	policy               = app.Flag("policy", "Policy for changing record sets: sync (create, update and delete), upsert-only (never delete), create-only (never change existing record sets not owned by --owner-id)").Default(controller.PolicySync).Enum(controller.Policies...)
+	sources              = app.Flag("source", "Kubernetes resources whose hosts are published, can be provided multiple times: ingress, service (of type LoadBalancer), httproute, gateway (Gateway API), istio-gateway, istio-virtualservice (Istio), dnsrecord (DNSRecord custom resources)").Default("ingress").Enums("ingress", "service", "httproute", "gateway", "istio-gateway", "istio-virtualservice", "dnsrecord")
+	maxDeletions         = app.Flag("max-deletions", "Maximum number of record set deletions within --deletion-window before deletions are held back, 0 disables the limit").Default("0").Int()
+	maxDeletionPercent   = app.Flag("max-deletion-percent", "Maximum percentage of managed hosts deleted within --deletion-window before deletions are held back, 0 disables the limit").Default("0").Float64()
+	deletionPercentHosts = app.Flag("deletion-percent-min-hosts", "Minimum number of managed hosts at the start of --deletion-window for --max-deletion-percent to apply").Default("10").Int()
+	deletionWindow       = app.Flag("deletion-window", "Time window for --max-deletions and --max-deletion-percent").Default("10m").Duration()
+	allowMassDeletion    = app.Flag("allow-mass-deletion", "if true, deletions are never held back by --max-deletions and --max-deletion-percent").Bool()
+	configFile           = app.Flag("config-file", "Optional YAML config file, which is reloaded on changes and takes precedence over the corresponding flags").String()
+	configReloadInterval = app.Flag("config-reload-interval", "Interval between checks of the config file for changes").Default("10s").Duration()
+	namespacePolicy      = app.Flag("namespace-policy", "if true, hosts must be granted to the namespace of the ingress resource by its annotation ingress.net/route53-domains").Bool()
+	tlsHosts             = app.Flag("tls-hosts", "if true, the hosts of spec.tls of ingress resources are published too, the ingress annotation ingress.net/route53-tls-hosts takes precedence").Bool()
+	listenAddress        = app.Flag("listen-address", "Address to listen on for the /metrics, /healthz, /readyz endpoints and the /gc and /drift endpoints of --trigger-endpoints").Default(":8080").String()
+	checkInterval        = app.Flag("route53-check-interval", "Interval between connectivity checks of the DNS provider").Default("1m").Duration()
+	readinessWindow      = app.Flag("readiness-window", "Maximum age of the last successful connectivity check of the DNS provider for the controller to be ready").Default("5m").Duration()
+	propagationTimeout   = app.Flag("propagation-timeout", "Time after which a warning event is emitted and polling stops if an Amazon Route53 change is still not INSYNC").Default("5m").Duration()
+	dnsProvider          = app.Flag("provider", "DNS provider whose record sets are managed: route53 (Amazon Route53), rfc2136 (name servers supporting dynamic updates, e.g. BIND), zonefile (BIND zone files in --zonefile-directory)").Default("route53").Enum("route53", "rfc2136", "zonefile")
+	rfc2136Host          = app.Flag("rfc2136-host", "Address of the primary name server for --provider=rfc2136, e.g. ns1.example.com:53").String()
+	rfc2136Zones         = app.Flag("rfc2136-zone", "Zone which is updated with --provider=rfc2136, can be provided multiple times").Strings()
+	rfc2136TSIGKeyName   = app.Flag("rfc2136-tsig-keyname", "Name of the TSIG key signing the updates with --provider=rfc2136, updates are not signed if empty").String()
+	rfc2136TSIGSecret    = app.Flag("rfc2136-tsig-secret", "Base64 encoded secret of the TSIG key, can be set by the environment variable RFC2136_TSIG_SECRET").Envar("RFC2136_TSIG_SECRET").String()
+	rfc2136TSIGAlgorithm = app.Flag("rfc2136-tsig-algorithm", "Algorithm of the TSIG key").Default("hmac-sha256").Enum(rfc2136.Algorithms...)
+	rfc2136Timeout       = app.Flag("rfc2136-timeout", "Timeout of queries, zone transfers and updates with --provider=rfc2136").Default("10s").Duration()
+	zonefileDirectory    = app.Flag("zonefile-directory", "Directory of the zone files written with --provider=zonefile").String()
+	zonefileZones        = app.Flag("zonefile-zone", "Zone which is written to <zone>.zone with --provider=zonefile, can be provided multiple times").Strings()
+	staticLoadBalancers  = app.Flag("static-load-balancer", "DNS name of a load balancer given by its name as NAME=DNSNAME, used instead of the AWS API with --provider=zonefile and by plan, can be provided multiple times").StringMap()
+	livenessTimeout      = app.Flag("liveness-timeout", "Maximum time handling a single event may take before the controller is reported as not alive").Default("5m").Duration()
+	gcInterval           = app.Flag("gc-interval", "Interval between garbage collections of orphaned record sets, which carry the ownership record of --owner-id but are not referenced by any resource, 0 disables the periodic garbage collection").Default("0").Duration()
+	driftInterval        = app.Flag("drift-interval", "Interval between drift detections comparing the live record sets of all managed hosts with the desired ones, 0 disables the periodic drift detection").Default("0").Duration()
+	fixDrift             = app.Flag("fix-drift", "if true, drifted record sets are corrected instead of only reported").Bool()
+	triggerEndpoints     = app.Flag("trigger-endpoints", "if true, the garbage collection and the drift detection can be triggered by POST /gc and POST /drift").Bool()
+	lbRefreshInterval    = app.Flag("load-balancer-refresh-interval", "Interval between refreshes of the cached DNS names and hosted zones of load balancers given by their name, hosts of changed load balancers are pointed to them again, 0 disables the cache").Default("0").Duration()
+	dryRun               = app.Flag("dry-run", "if true, changes of record sets are only logged instead of submitted").Bool()
+	adopt                = app.Flag("adopt", "if true, existing record sets not owned by --owner-id are adopted by writing their ownership record if they match the desired record set, differing ones are reported and kept unchanged").Bool()
+	runCommand           = app.Command("run", "Run the controller (default)").Default()
+	planCommand          = app.Command("plan", "Print the record sets planned for the ingress resources of manifest files without contacting a cluster or Amazon Route53")
+	planFiles            = planCommand.Flag("filename", "Manifest file or directory of manifest files (.yaml, .yml, .json), can be provided multiple times").Short('f').Required().Strings()
+	planOutput           = planCommand.Flag("output", "Output format, one of: [table, json]").Short('o').Default("table").Enum("table", "json")
 	//Here you can define more flags for your application
 )
 
@@ -37,7 +93,7 @@ func main() {
 	logflag.AddFlags(app, &logcfg)
 	k8sflag.AddFlags(app, &runOutsideCluster)
 	//Parse all arguments
-	_, err := app.Parse(os.Args[1:])
+	command, err := app.Parse(os.Args[1:])
 	if err != nil {
 		//Received error while parsing arguments from function app.Parse
 		fmt.Fprintln(os.Stderr, "Catched the following error while parsing arguments: ", err)
@@ -53,6 +109,9 @@ func main() {
 	}
 	//First usage of initialized logger for testing
 	level.Debug(logger).Log("msg", "Logging initiated...")
+	if command == planCommand.FullCommand() {
+		os.Exit(plan(logger))
+	}
 	//Initialize new k8s client from common k8s package
 	k8sClient, err := kubernetes.NewClientSet(runOutsideCluster)
 	if err != nil {
@@ -68,18 +127,316 @@ func main() {
 
 	wg := &sync.WaitGroup{} // Goroutines can add themselves to this to be waited on so that they finish
 
-	//Initialize new k8s ingress-controller from common k8s package
-	ingressController := &ingress.IngressController{}
-	//-TODO: DEPRECATE
-	ingressController.Controller = controller.New(logger, *allowlistPrefix, *allowlistSuffix, *deleteAlias, *deleteCname, *dNSType)
-	ingressController.Initialize(k8sClient)
-	//Run initiated ingress-controller as go routine
-	go ingressController.Run(stop, wg)
+	flagConfig := newFlagConfig()
+	var ingressController *controller.Controller
+	var configWatcher *config.Watcher
+	var controllerConfig controller.Config
+	if *configFile != "" {
+		configWatcher = config.NewWatcher(logger, *configFile, *configReloadInterval, flagConfig, func(c controller.Config) error {
+			return ingressController.ApplyConfig(c)
+		})
+		controllerConfig, err = configWatcher.Load()
+	} else {
+		controllerConfig, err = flagConfig.Build()
+	}
+	if err != nil {
+		level.Error(logger).Log("msg", err.Error())
+		app.Usage(os.Args[1:])
+		os.Exit(2)
+	}
+	if *deleteAlias || *deleteCname {
+		level.Warn(logger).Log("msg", "--delete-alias and --delete-cname are deprecated and ignored, record set types are migrated automatically")
+	}
+	//Initialize the DNS provider
+	var recordProvider provider.Provider
+	switch *dnsProvider {
+	case "rfc2136":
+		recordProvider, err = rfc2136.New(rfc2136.Config{
+			Server:        *rfc2136Host,
+			Zones:         *rfc2136Zones,
+			TSIGKeyName:   *rfc2136TSIGKeyName,
+			TSIGSecret:    *rfc2136TSIGSecret,
+			TSIGAlgorithm: *rfc2136TSIGAlgorithm,
+			Timeout:       *rfc2136Timeout,
+		})
+		if err != nil {
+			level.Error(logger).Log("msg", err.Error())
+			app.Usage(os.Args[1:])
+			os.Exit(2)
+		}
+	case "zonefile":
+		recordProvider, err = zonefile.New(zonefile.Config{
+			Directory: *zonefileDirectory,
+			Zones:     *zonefileZones,
+			TTL:       *ttl,
+		})
+		if err != nil {
+			level.Error(logger).Log("msg", err.Error())
+			app.Usage(os.Args[1:])
+			os.Exit(2)
+		}
+	default:
+		recordProvider = aws.NewProvider()
+	}
+	level.Info(logger).Log("msg", "Using DNS provider", "provider", *dnsProvider)
+	ingressController = controller.New(logger, k8sClient, recordProvider, controllerConfig, *namespacePolicy, *tlsHosts, *policy, controller.DeletionLimits{
+		MaxDeletions: *maxDeletions,
+		MaxPercent:   *maxDeletionPercent,
+		MinHosts:     *deletionPercentHosts,
+		Window:       *deletionWindow,
+		Override:     *allowMassDeletion,
+	}, *propagationTimeout)
+	if *dryRun {
+		level.Warn(logger).Log("msg", "Dry run: changes of record sets are only logged")
+		ingressController.EnableDryRun()
+	}
+	if *dnsProvider == "zonefile" {
+		//Never look up load balancers with the AWS API
+		ingressController.UseStaticLoadBalancers(*staticLoadBalancers)
+		if *lbRefreshInterval > 0 {
+			level.Warn(logger).Log("msg", "--load-balancer-refresh-interval is ignored with --provider=zonefile")
+			*lbRefreshInterval = 0
+		}
+	} else if len(*staticLoadBalancers) > 0 {
+		level.Warn(logger).Log("msg", "--static-load-balancer is ignored without --provider=zonefile")
+	}
+	if *lbRefreshInterval > 0 {
+		ingressController.EnableLoadBalancerCache()
+	}
+	if *fixDrift {
+		ingressController.EnableDriftFix()
+	}
+	if *adopt {
+		if controllerConfig.OwnerID == "" {
+			level.Warn(logger).Log("msg", "--adopt has no effect without --owner-id")
+		}
+		ingressController.EnableAdoption()
+	}
+	if configWatcher != nil {
+		//Reload config file on changes
+		wg.Add(1)
+		go configWatcher.Run(stop, wg)
+	}
+	//Initialize the dynamic client for sources which are custom resources
+	httpRoutes, istioGateways, virtualServices := false, false, false
+	var dynamicClient dynamic.Interface
+	for _, source := range *sources {
+		switch source {
+		case "httproute", "gateway", "istio-gateway", "istio-virtualservice", "dnsrecord":
+			httpRoutes = httpRoutes || source == "httproute"
+			istioGateways = istioGateways || source == "istio-gateway"
+			virtualServices = virtualServices || source == "istio-virtualservice"
+			if dynamicClient == nil {
+				dynamicClient, err = newDynamicClient(runOutsideCluster)
+				if err != nil {
+					level.Error(logger).Log("msg", err.Error())
+					os.Exit(1)
+				}
+				ingressController.UseDynamicClient(dynamicClient)
+			}
+		}
+	}
+
+	//Cache the namespaces for the namespace policy before any event is handled
+	var synced []cache.InformerSynced
+	if *namespacePolicy {
+		namespaceInformer := informer.NewNamespaceInformer(k8sClient)
+		ingressController.UseNamespaceLister(corelisters.NewNamespaceLister(namespaceInformer.Indexer()))
+		wg.Add(1)
+		go namespaceInformer.Run(stop, wg)
+		if !cache.WaitForCacheSync(stop, namespaceInformer.HasSynced) {
+			level.Error(logger).Log("msg", "Could not sync the namespace cache")
+			os.Exit(1)
+		}
+		synced = append(synced, namespaceInformer.HasSynced)
+	}
+
+	//Cache the services for the load balancers of Istio gateways, changed load balancers repoint the gateways
+	var serviceInformer *informer.Informer
+	if istioGateways || virtualServices {
+		serviceInformer = informer.NewServiceInformer(k8sClient, ingressController.IstioServices())
+		ingressController.UseServiceLister(corelisters.NewServiceLister(serviceInformer.Indexer()))
+		wg.Add(1)
+		go serviceInformer.Run(stop, wg)
+		if !cache.WaitForCacheSync(stop, serviceInformer.HasSynced) {
+			level.Error(logger).Log("msg", "Could not sync the service cache")
+			os.Exit(1)
+		}
+		synced = append(synced, serviceInformer.HasSynced)
+	}
+
+	//Cache the gateways for the targets of HTTP routes, changed addresses repoint the routes
+	var gatewayInformer *informer.Informer
+	if httpRoutes {
+		gatewayInformer = informer.NewDynamicInformer(dynamicClient, controller.GatewayResource, nil)
+		ingressController.UseGatewayLister(cache.NewGenericLister(gatewayInformer.Indexer(), controller.GatewayResource.GroupResource()))
+		wg.Add(1)
+		go gatewayInformer.Run(stop, wg)
+		if !cache.WaitForCacheSync(stop, gatewayInformer.HasSynced) {
+			level.Error(logger).Log("msg", "Could not sync the gateway cache")
+			os.Exit(1)
+		}
+		synced = append(synced, gatewayInformer.HasSynced)
+	}
+
+	//Initialize new informers for all sources which pass events to the controller
+	var sourceInformers []*informer.Informer
+	for _, source := range *sources {
+		var sourceInformer *informer.Informer
+		switch source {
+		case "ingress":
+			sourceInformer = informer.NewIngressInformer(k8sClient, ingressController)
+		case "service":
+			if serviceInformer != nil {
+				//The running service informer of the Istio sources passes its events to the service handler as well
+				serviceInformer.AddController(ingressController.Services())
+				level.Info(logger).Log("msg", "Watching source", "source", source)
+				continue
+			}
+			sourceInformer = informer.NewServiceInformer(k8sClient, ingressController.Services())
+		case "httproute":
+			sourceInformer = informer.NewDynamicInformer(dynamicClient, controller.HTTPRouteResource, ingressController.HTTPRoutes())
+			ingressController.UseHTTPRouteLister(cache.NewGenericLister(sourceInformer.Indexer(), controller.HTTPRouteResource.GroupResource()))
+		case "gateway":
+			if gatewayInformer != nil {
+				//The running gateway informer of the HTTP route source passes its events to the gateway handler as well
+				gatewayInformer.AddController(ingressController.Gateways(httpRoutes))
+				level.Info(logger).Log("msg", "Watching source", "source", source)
+				continue
+			}
+			sourceInformer = informer.NewDynamicInformer(dynamicClient, controller.GatewayResource, ingressController.Gateways(httpRoutes))
+		case "istio-gateway":
+			sourceInformer = informer.NewDynamicInformer(dynamicClient, controller.IstioGatewayResource, ingressController.IstioGateways(virtualServices))
+			ingressController.UseIstioGatewayLister(cache.NewGenericLister(sourceInformer.Indexer(), controller.IstioGatewayResource.GroupResource()))
+		case "istio-virtualservice":
+			sourceInformer = informer.NewDynamicInformer(dynamicClient, controller.VirtualServiceResource, ingressController.VirtualServices())
+			ingressController.UseVirtualServiceLister(cache.NewGenericLister(sourceInformer.Indexer(), controller.VirtualServiceResource.GroupResource()))
+		case "dnsrecord":
+			sourceInformer = informer.NewDynamicInformer(dynamicClient, controller.DNSRecordResource, ingressController.DNSRecords())
+		}
+		level.Info(logger).Log("msg", "Watching source", "source", source)
+		sourceInformers = append(sourceInformers, sourceInformer)
+	}
+	//Run initiated informers as go routines once all caches are set
+	for _, sourceInformer := range sourceInformers {
+		wg.Add(1)
+		go sourceInformer.Run(stop, wg)
+		synced = append(synced, sourceInformer.HasSynced)
+	}
+
+	//Poll the status of submitted changes
+	wg.Add(1)
+	go ingressController.RunPropagationPoller(stop, wg)
+
+	//Write the statuses of the resources
+	wg.Add(1)
+	go ingressController.RunStatusWriter(stop, wg)
+
+	//Check health periodically
+	checker := health.New(logger, recordProvider, *checkInterval, *readinessWindow, *livenessTimeout, ingressController.Stalled, synced...)
+	wg.Add(1)
+	go checker.Run(stop, wg)
+
+	//Retry held back deletions periodically
+	if *maxDeletions > 0 || *maxDeletionPercent > 0 {
+		wg.Add(1)
+		go ingressController.RunDeletionGuard(stop, wg)
+	}
+
+	//Delete orphaned record sets periodically
+	if *gcInterval > 0 {
+		wg.Add(1)
+		go ingressController.RunGarbageCollection(*gcInterval, stop, wg, synced...)
+	}
+
+	//Detect drifted record sets periodically
+	if *driftInterval > 0 {
+		wg.Add(1)
+		go ingressController.RunDriftDetection(*driftInterval, stop, wg, synced...)
+	}
+
+	//Refresh the cached load balancers periodically
+	if *lbRefreshInterval > 0 {
+		wg.Add(1)
+		go ingressController.RunLoadBalancerWatcher(*lbRefreshInterval, stop, wg, synced...)
+	}
+
+	//Serve prometheus metrics, probes, the garbage collection and the drift detection
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	mux.HandleFunc("/healthz", checker.Healthz)
+	mux.HandleFunc("/readyz", checker.Readyz)
+	if *triggerEndpoints {
+		mux.HandleFunc("/gc", triggerHandler("garbage collection", synced, func() interface{} {
+			return ingressController.CollectGarbage()
+		}))
+		mux.HandleFunc("/drift", triggerHandler("drift detection", synced, func() interface{} {
+			return ingressController.DetectDrift()
+		}))
+	}
+	server := &http.Server{Addr: *listenAddress, Handler: mux, ReadTimeout: 10 * time.Second}
+	go func() {
+		level.Info(logger).Log("msg", "Listening for metrics and probes", "address", *listenAddress)
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			level.Error(logger).Log("msg", err.Error())
+			os.Exit(1)
+		}
+	}()
 
 	<-sigs // Wait for signals (this hangs until a signal arrives)
 
 	level.Info(logger).Log("msg", "Shutting down...")
 
 	close(stop) // Tell goroutines to stop themselves
-	wg.Wait()   // Wait for all to be stopped
+	server.Close()
+	wg.Wait() // Wait for all to be stopped
+}
+
+// return the settings from flags, which are overridden by the config file
+func newFlagConfig() config.File {
+	return config.File{
+		Allowlist: config.Allowlist{
+			Prefixes: config.SplitList(*allowlistPrefix),
+			Suffixes: config.SplitList(*allowlistSuffix),
+			Globs:    config.SplitList(*allowlistGlob),
+			Regexes:  *allowlistRegex,
+		},
+		Denylist: config.SplitList(*denylist),
+		Defaults: config.Defaults{
+			TTL:     *ttl,
+			DNSType: *dNSType,
+		},
+		OwnerID: *ownerID,
+	}
+}
+
+// create a client for custom resources using the same configuration as the kubernetes client set
+func newDynamicClient(runOutsideCluster bool) (dynamic.Interface, error) {
+	kubeConfigLocation := ""
+	if runOutsideCluster {
+		kubeConfigLocation = filepath.Join(os.Getenv("HOME"), ".kube", "config")
+	}
+	restConfig, err := clientcmd.BuildConfigFromFlags("", kubeConfigLocation)
+	if err != nil {
+		return nil, err
+	}
+	return dynamic.NewForConfig(restConfig)
+}
+
+// return a handler running the operation on POST once all informers have synced and responding with its result as JSON
+func triggerHandler(operation string, synced []cache.InformerSynced, run func() interface{}) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, operation+" must be triggered by POST", http.StatusMethodNotAllowed)
+			return
+		}
+		for _, informerSynced := range synced {
+			if !informerSynced() {
+				http.Error(w, "informers have not synced yet", http.StatusServiceUnavailable)
+				return
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(run())
+	}
 }
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/dbsystel/AmazonRoute53-ingress-controller/config"
+	"github.com/dbsystel/AmazonRoute53-ingress-controller/controller"
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"k8s.io/api/networking/v1beta1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/yaml"
+)
+
+// print the record sets planned for the ingress resources of the manifest files and return the exit code:
+// 0 if all hosts are allowed, 1 if a host is not allowed or invalid, 2 if the manifests could not be read
+func plan(logger log.Logger) int {
+	controllerConfig, err := planConfig()
+	if err != nil {
+		level.Error(logger).Log("msg", err.Error())
+		return 2
+	}
+	planner := controller.NewPlanner(logger, controllerConfig, *tlsHosts, *staticLoadBalancers)
+
+	var ingresses []*v1beta1.Ingress
+	for _, path := range *planFiles {
+		found, err := readIngresses(path)
+		if err != nil {
+			level.Error(logger).Log("msg", "Could not read manifests", "path", path, "err", err.Error())
+			return 2
+		}
+		ingresses = append(ingresses, found...)
+	}
+
+	planned := []controller.PlannedRecord{}
+	for _, ingressObj := range ingresses {
+		planned = append(planned, planner.Plan(ingressObj)...)
+	}
+	if *planOutput == "json" {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(planned)
+	} else {
+		printPlan(os.Stdout, planned)
+	}
+
+	for _, record := range planned {
+		if !record.Allowed {
+			return 1
+		}
+	}
+	return 0
+}
+
+// return the controller config from the flags and the config file
+func planConfig() (controller.Config, error) {
+	flagConfig := newFlagConfig()
+	if *configFile == "" {
+		return flagConfig.Build()
+	}
+	file, err := config.Load(*configFile)
+	if err != nil {
+		return controller.Config{}, err
+	}
+	return flagConfig.Merge(file).Build()
+}
+
+// print the planned records as table
+func printPlan(out io.Writer, planned []controller.PlannedRecord) {
+	writer := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(writer, "RESOURCE\tHOST\tTYPE\tTTL\tVALUE\tALLOWED\tREASON")
+	for _, record := range planned {
+		ttl := ""
+		if record.TTL > 0 {
+			ttl = strconv.FormatInt(record.TTL, 10)
+		}
+		fmt.Fprintf(writer, "%s\t%s\t%s\t%s\t%s\t%t\t%s\n", record.Resource, record.Host, record.Type, ttl, record.Value, record.Allowed, record.Reason)
+	}
+	writer.Flush()
+}
+
+// read the ingress resources of the manifest file or of all manifest files in the directory, other resources are skipped
+func readIngresses(path string) ([]*v1beta1.Ingress, error) {
+	var ingresses []*v1beta1.Ingress
+	err := filepath.Walk(path, func(file string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		switch strings.ToLower(filepath.Ext(file)) {
+		case ".yaml", ".yml", ".json":
+		default:
+			if file != path {
+				return nil
+			}
+		}
+		found, err := readManifest(file)
+		if err != nil {
+			return fmt.Errorf("%s: %v", file, err)
+		}
+		ingresses = append(ingresses, found...)
+		return nil
+	})
+	return ingresses, err
+}
+
+// read the ingress resources of the documents of the manifest file, lists are expanded
+func readManifest(file string) ([]*v1beta1.Ingress, error) {
+	reader, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	var ingresses []*v1beta1.Ingress
+	decoder := yaml.NewYAMLOrJSONDecoder(reader, 4096)
+	for {
+		obj := &unstructured.Unstructured{}
+		if err := decoder.Decode(&obj.Object); err == io.EOF {
+			return ingresses, nil
+		} else if err != nil {
+			return nil, err
+		}
+		if obj.Object == nil {
+			// empty document
+			continue
+		}
+
+		objs := []unstructured.Unstructured{*obj}
+		if obj.IsList() {
+			list, err := obj.ToList()
+			if err != nil {
+				return nil, err
+			}
+			objs = list.Items
+		}
+		for _, item := range objs {
+			// ingress resources of extensions/v1beta1, networking.k8s.io/v1beta1 and networking.k8s.io/v1 share the fields
+			// of their hosts, only the backends of networking.k8s.io/v1 differ and are not needed for planning
+			if item.GetKind() != "Ingress" {
+				continue
+			}
+			switch item.GroupVersionKind().Version {
+			case "v1beta1":
+			case "v1":
+				unstructured.RemoveNestedField(item.Object, "spec", "defaultBackend")
+				rules, _, _ := unstructured.NestedSlice(item.Object, "spec", "rules")
+				for _, rule := range rules {
+					if rule, ok := rule.(map[string]interface{}); ok {
+						delete(rule, "http")
+					}
+				}
+				if rules != nil {
+					unstructured.SetNestedSlice(item.Object, rules, "spec", "rules")
+				}
+			default:
+				continue
+			}
+			ingressObj := &v1beta1.Ingress{}
+			if err := runtime.DefaultUnstructuredConverter.FromUnstructured(item.Object, ingressObj); err != nil {
+				return nil, err
+			}
+			if ingressObj.Namespace == "" {
+				ingressObj.Namespace = "default"
+			}
+			ingresses = append(ingresses, ingressObj)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/dbsystel/AmazonRoute53-ingress-controller/controller"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// default TTL of CNAME and ownership records
+const defaultTTL = 300
+
+// File is the content of the YAML configuration file
+type File struct {
+	Allowlist Allowlist         `yaml:"allowlist"`
+	Denylist  []string          `yaml:"denylist"`
+	Defaults  Defaults          `yaml:"defaults"`
+	ZoneRoles map[string]string `yaml:"zoneRoles"`
+	OwnerID   string            `yaml:"ownerID"`
+}
+
+// Allowlist holds the rules for hosts which may be managed
+type Allowlist struct {
+	Prefixes []string `yaml:"prefixes"`
+	Suffixes []string `yaml:"suffixes"`
+	Globs    []string `yaml:"globs"`
+	Regexes  []string `yaml:"regexes"`
+}
+
+// Defaults holds the defaults for created record sets
+type Defaults struct {
+	TTL     int64  `yaml:"ttl"`
+	DNSType string `yaml:"dnsType"`
+}
+
+// Load reads and parses the configuration file
+func Load(path string) (*File, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(content)
+}
+
+// Parse parses the content of a configuration file, unknown fields are rejected
+func Parse(content []byte) (*File, error) {
+	file := &File{}
+	if err := yaml.UnmarshalStrict(content, file); err != nil {
+		return nil, err
+	}
+	return file, nil
+}
+
+// Merge returns a copy of base where every setting defined in the given file takes precedence.
+// A list or map which is given empty, e.g. "globs: []", replaces the base, only an omitted or null one keeps it.
+func (base File) Merge(file *File) File {
+	if file == nil {
+		return base
+	}
+	merged := base
+	if file.Allowlist.Prefixes != nil {
+		merged.Allowlist.Prefixes = file.Allowlist.Prefixes
+	}
+	if file.Allowlist.Suffixes != nil {
+		merged.Allowlist.Suffixes = file.Allowlist.Suffixes
+	}
+	if file.Allowlist.Globs != nil {
+		merged.Allowlist.Globs = file.Allowlist.Globs
+	}
+	if file.Allowlist.Regexes != nil {
+		merged.Allowlist.Regexes = file.Allowlist.Regexes
+	}
+	if file.Denylist != nil {
+		merged.Denylist = file.Denylist
+	}
+	if file.Defaults.TTL != 0 {
+		merged.Defaults.TTL = file.Defaults.TTL
+	}
+	if file.Defaults.DNSType != "" {
+		merged.Defaults.DNSType = file.Defaults.DNSType
+	}
+	if file.ZoneRoles != nil {
+		merged.ZoneRoles = file.ZoneRoles
+	}
+	if file.OwnerID != "" {
+		merged.OwnerID = file.OwnerID
+	}
+	return merged
+}
+
+// Build validates the settings and returns the resulting controller config
+func (f File) Build() (controller.Config, error) {
+	config := controller.Config{}
+
+	allowlist, err := controller.NewAllowlist(f.Allowlist.Prefixes, f.Allowlist.Suffixes, f.Allowlist.Globs, f.Allowlist.Regexes, f.Denylist)
+	if err != nil {
+		return config, fmt.Errorf("invalid allowlist: %v", err)
+	}
+	config.Allowlist = allowlist
+
+	switch strings.ToLower(f.Defaults.DNSType) {
+	case "alias", "cname":
+		config.DNSType = f.Defaults.DNSType
+	default:
+		return config, fmt.Errorf("invalid dns type %q, must be one of: [alias, cname]", f.Defaults.DNSType)
+	}
+
+	config.TTL = f.Defaults.TTL
+	if config.TTL == 0 {
+		config.TTL = defaultTTL
+	}
+	if config.TTL < 0 || config.TTL > 2147483647 {
+		return config, fmt.Errorf("invalid ttl %d, must be between 0 and 2147483647", config.TTL)
+	}
+
+	for zone, roleARN := range f.ZoneRoles {
+		if zone == "" {
+			return config, errors.New("invalid zone role mapping: zone name must not be empty")
+		}
+		if !strings.HasPrefix(roleARN, "arn:") {
+			return config, fmt.Errorf("invalid zone role mapping for zone %s: %q is not an IAM role ARN", zone, roleARN)
+		}
+	}
+	config.ZoneRoles = f.ZoneRoles
+
+	if strings.ContainsAny(f.OwnerID, ",=\"") {
+		return config, fmt.Errorf("invalid owner ID %q, must not contain any of: , = \"", f.OwnerID)
+	}
+	config.OwnerID = f.OwnerID
+
+	return config, nil
+}
+
+// SplitList splits a comma separated list as used by flags
+func SplitList(list string) []string {
+	if list == "" {
+		return nil
+	}
+	return strings.Split(list, ",")
+}
@@ -0,0 +1,109 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMerge(t *testing.T) {
+	base := File{
+		Allowlist: Allowlist{Suffixes: []string{".example.com"}, Globs: []string{"*.example.org"}},
+		Denylist:  []string{"db.example.com"},
+		Defaults:  Defaults{TTL: 60, DNSType: "cname"},
+		ZoneRoles: map[string]string{"example.com": "arn:aws:iam::1:role/a"},
+		OwnerID:   "base",
+	}
+
+	tests := []struct {
+		name    string
+		content string
+		merged  File
+	}{
+		{
+			name:    "empty file keeps the base",
+			content: "",
+			merged:  base,
+		},
+		{
+			name:    "null keeps the base",
+			content: "allowlist:\n  globs:\ndenylist:\nzoneRoles:\n",
+			merged:  base,
+		},
+		{
+			name:    "empty lists replace the base",
+			content: "allowlist:\n  globs: []\ndenylist: []\nzoneRoles: {}\n",
+			merged: File{
+				Allowlist: Allowlist{Suffixes: []string{".example.com"}, Globs: []string{}},
+				Denylist:  []string{},
+				Defaults:  Defaults{TTL: 60, DNSType: "cname"},
+				ZoneRoles: map[string]string{},
+				OwnerID:   "base",
+			},
+		},
+		{
+			name:    "defined settings take precedence",
+			content: "allowlist:\n  prefixes: [internal-]\ndefaults:\n  ttl: 30\n  dnsType: alias\nownerID: file\n",
+			merged: File{
+				Allowlist: Allowlist{Prefixes: []string{"internal-"}, Suffixes: []string{".example.com"}, Globs: []string{"*.example.org"}},
+				Denylist:  []string{"db.example.com"},
+				Defaults:  Defaults{TTL: 30, DNSType: "alias"},
+				ZoneRoles: map[string]string{"example.com": "arn:aws:iam::1:role/a"},
+				OwnerID:   "file",
+			},
+		},
+	}
+	for _, test := range tests {
+		file, err := Parse([]byte(test.content))
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		if merged := base.Merge(file); !reflect.DeepEqual(merged, test.merged) {
+			t.Errorf("%s: got %+v, want %+v", test.name, merged, test.merged)
+		}
+	}
+}
+
+func TestParseRejectsUnknownFields(t *testing.T) {
+	if _, err := Parse([]byte("allowlist:\n  suffix: [.example.com]\n")); err == nil {
+		t.Error("expected an error for an unknown field")
+	}
+}
+
+func TestBuild(t *testing.T) {
+	tests := []struct {
+		name  string
+		file  File
+		valid bool
+		ttl   int64
+	}{
+		{"defaults", File{Defaults: Defaults{DNSType: "cname"}}, true, defaultTTL},
+		{"alias in upper case", File{Defaults: Defaults{DNSType: "ALIAS", TTL: 60}}, true, 60},
+		{"invalid dns type", File{Defaults: Defaults{DNSType: "a"}}, false, 0},
+		{"negative ttl", File{Defaults: Defaults{DNSType: "cname", TTL: -1}}, false, 0},
+		{"invalid regex", File{Allowlist: Allowlist{Regexes: []string{"("}}, Defaults: Defaults{DNSType: "cname"}}, false, 0},
+		{"empty zone", File{Defaults: Defaults{DNSType: "cname"}, ZoneRoles: map[string]string{"": "arn:aws:iam::1:role/a"}}, false, 0},
+		{"invalid role", File{Defaults: Defaults{DNSType: "cname"}, ZoneRoles: map[string]string{"example.com": "role"}}, false, 0},
+		{"invalid owner ID", File{Defaults: Defaults{DNSType: "cname"}, OwnerID: "a,b"}, false, 0},
+		{"owner ID", File{Defaults: Defaults{DNSType: "cname"}, OwnerID: "cluster-1"}, true, defaultTTL},
+	}
+	for _, test := range tests {
+		config, err := test.file.Build()
+		if (err == nil) != test.valid {
+			t.Errorf("%s: got error %v, want valid %v", test.name, err, test.valid)
+			continue
+		}
+		if test.valid && config.TTL != test.ttl {
+			t.Errorf("%s: got ttl %d, want %d", test.name, config.TTL, test.ttl)
+		}
+	}
+}
+
+func TestSplitList(t *testing.T) {
+	if list := SplitList(""); list != nil {
+		t.Errorf("SplitList(\"\") = %q, want nil", list)
+	}
+	if list := SplitList("a,b"); !reflect.DeepEqual(list, []string{"a", "b"}) {
+		t.Errorf("SplitList(\"a,b\") = %q", list)
+	}
+}
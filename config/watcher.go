@@ -0,0 +1,112 @@
+package config
+
+import (
+	"crypto/sha256"
+	"io/ioutil"
+	"sync"
+	"time"
+
+	"github.com/dbsystel/AmazonRoute53-ingress-controller/controller"
+	"github.com/dbsystel/AmazonRoute53-ingress-controller/metrics"
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+)
+
+// Watcher reloads the configuration file whenever its content changes and applies it to the controller
+type Watcher struct {
+	logger   log.Logger
+	path     string
+	interval time.Duration
+	base     File
+	apply    func(controller.Config) error
+	checksum [sha256.Size]byte
+}
+
+// NewWatcher creates a new object from type Watcher and return object pointer.
+// Settings missing in the configuration file are taken from base, a config rejected by apply is not applied.
+func NewWatcher(logger log.Logger, path string, interval time.Duration, base File, apply func(controller.Config) error) *Watcher {
+	watcher := &Watcher{}
+	watcher.logger = logger
+	watcher.path = path
+	watcher.interval = interval
+	watcher.base = base
+	watcher.apply = apply
+	return watcher
+}
+
+// Load reads the configuration file and returns the resulting controller config without applying it
+func (w *Watcher) Load() (controller.Config, error) {
+	content, err := ioutil.ReadFile(w.path)
+	if err != nil {
+		return controller.Config{}, err
+	}
+	config, err := w.build(content)
+	if err != nil {
+		return config, err
+	}
+	w.checksum = sha256.Sum256(content)
+	metrics.ConfigLastReloadSuccessful.Set(1)
+	metrics.ConfigLastReloadSuccessTimestamp.SetToCurrentTime()
+	return config, nil
+}
+
+// Run checks the configuration file for changes periodically until stopCh is closed
+func (w *Watcher) Run(stopCh <-chan struct{}, wg *sync.WaitGroup) {
+	defer wg.Done()
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			w.reload()
+		case <-stopCh:
+			return
+		}
+	}
+}
+
+// reload the configuration file if its content changed, an invalid file leaves the current config in place
+func (w *Watcher) reload() {
+	content, err := ioutil.ReadFile(w.path)
+	if err != nil {
+		level.Error(w.logger).Log("msg", "Could not read config file, keeping current config", "file", w.path, "err", err.Error())
+		metrics.ConfigReloads.WithLabelValues("failure").Inc()
+		metrics.ConfigLastReloadSuccessful.Set(0)
+		return
+	}
+	checksum := sha256.Sum256(content)
+	if checksum == w.checksum {
+		return
+	}
+	w.checksum = checksum
+
+	level.Info(w.logger).Log("msg", "Config file changed, reloading", "file", w.path)
+	config, err := w.build(content)
+	if err != nil {
+		level.Error(w.logger).Log("msg", "Invalid config file, keeping current config", "file", w.path, "err", err.Error())
+		metrics.ConfigReloads.WithLabelValues("failure").Inc()
+		metrics.ConfigLastReloadSuccessful.Set(0)
+		return
+	}
+
+	if err := w.apply(config); err != nil {
+		level.Error(w.logger).Log("msg", "Config rejected, keeping current config", "file", w.path, "err", err.Error())
+		metrics.ConfigReloads.WithLabelValues("failure").Inc()
+		metrics.ConfigLastReloadSuccessful.Set(0)
+		return
+	}
+	metrics.ConfigReloads.WithLabelValues("success").Inc()
+	metrics.ConfigLastReloadSuccessful.Set(1)
+	metrics.ConfigLastReloadSuccessTimestamp.SetToCurrentTime()
+}
+
+// parse the content of the configuration file, merge it into the base settings and validate the result
+func (w *Watcher) build(content []byte) (controller.Config, error) {
+	file, err := Parse(content)
+	if err != nil {
+		return controller.Config{}, err
+	}
+	return w.base.Merge(file).Build()
+}
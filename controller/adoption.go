@@ -0,0 +1,136 @@
+package controller
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/service/route53"
+	"github.com/dbsystel/AmazonRoute53-ingress-controller/aws"
+	"github.com/dbsystel/AmazonRoute53-ingress-controller/metrics"
+	"github.com/go-kit/kit/log/level"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// EnableAdoption makes the controller adopt existing record sets which are not owned by it instead of overwriting them
+func (c *Controller) EnableAdoption() {
+	c.adoption = true
+}
+
+// decisions about an existing record set in adoption mode
+const (
+	// the record set is not left to the adoption, it is changed like any other
+	adoptionManaged = "managed"
+	// the record set has no ownership record, it is adopted if it matches the desired one
+	adoptionAdopt = "adopt"
+	// the ownership record of the record set belongs to another owner, it is neither adopted nor changed
+	adoptionConflict = "conflict"
+)
+
+// decide about the live record set of the host in adoption mode by its ownership record. Existing record sets without
+// ownership record are left to the adoption, those owned by another owner are never deleted, overwritten or adopted.
+// Returns the decision and the other owner on conflict.
+func (c *Controller) decideAdoption(host string, live *route53.ResourceRecordSet, hostedZoneID, roleARN string) (string, string, error) {
+	if !c.adoption || live == nil || c.config.OwnerID == "" {
+		return adoptionManaged, "", nil
+	}
+	ownership, err := c.liveRecordSetOfType(ownershipName(host), route53.RRTypeTxt, hostedZoneID, roleARN)
+	if err != nil {
+		return "", "", err
+	}
+	decision, owner := adoptionDecision(live, ownership, c.config.OwnerID)
+	return decision, owner, nil
+}
+
+// decide about the live record set by its ownership record, both may be nil. An ownership record which was not
+// written by this controller belongs to an unknown owner.
+func adoptionDecision(live, ownership *route53.ResourceRecordSet, ownerID string) (string, string) {
+	if live == nil {
+		return adoptionManaged, ""
+	}
+	if ownership == nil {
+		return adoptionAdopt, ""
+	}
+	owner := "unknown"
+	if parsed := parseOwnership(ownership); parsed != nil {
+		owner = parsed["owner"]
+	}
+	if owner == ownerID {
+		return adoptionManaged, ""
+	}
+	return adoptionConflict, owner
+}
+
+// report that the existing record set of the host is owned by another owner and is kept unchanged
+func (c *Controller) reportOwnershipConflict(host, owner string, sourceObj recordSource) {
+	metrics.Adoptions.WithLabelValues("conflict").Inc()
+	level.Warn(c.logger).Log("msg", "Existing Route53 record set is owned by another owner, it is neither adopted nor changed", "owner", owner, "hostName", host, "resource", sourceKey(sourceObj))
+	c.recorder.Eventf(sourceObj, corev1.EventTypeWarning, "OwnershipConflict", "Existing Route53 record set of host %s is owned by %s and is kept", host, owner)
+}
+
+// adopt the live record set of the host if it matches the desired one: only the ownership record is written, the record set
+// is kept unchanged. A record set differing from the desired one is kept as well and reported. Returns the difference.
+func (c *Controller) adopt(host string, live, desired *route53.ResourceRecordSet, hostedZoneID, roleARN string, sourceObj recordSource) (string, error) {
+	if difference := recordSetDifference(live, desired); difference != "" {
+		metrics.Adoptions.WithLabelValues("differs").Inc()
+		level.Warn(c.logger).Log("msg", "Existing Route53 record set differs from the desired one, it is neither adopted nor changed", "difference", difference, "hostName", host, "resource", sourceKey(sourceObj))
+		c.recorder.Eventf(sourceObj, corev1.EventTypeWarning, "AdoptionSkipped", "Existing Route53 record set of host %s differs from the desired one and is kept: %s", host, difference)
+		return difference, nil
+	}
+
+	changes := []*route53.Change{aws.NewTXTChange(route53.ChangeActionUpsert, ownershipName(host), c.ownershipValue(sourceObj), c.config.TTL)}
+	changeID, err := c.submitChanges(hostedZoneID, roleARN, changes)
+	if err != nil {
+		metrics.Adoptions.WithLabelValues("error").Inc()
+		return "", err
+	}
+	metrics.Adoptions.WithLabelValues("adopted").Inc()
+	level.Info(c.logger).Log("msg", "Adopted existing Route53 record set", "changeID", changeID, "hostName", host, "resource", sourceKey(sourceObj))
+	c.recorder.Eventf(sourceObj, corev1.EventTypeNormal, "RecordAdopted", "Existing Route53 record set of host %s is adopted unchanged", host)
+	return "", nil
+}
+
+// return a description of the differences of type and target between the live and the desired record set, or an empty string.
+// TTLs are not compared.
+func recordSetDifference(live, desired *route53.ResourceRecordSet) string {
+	if live.AliasTarget != nil && desired.AliasTarget == nil || live.AliasTarget == nil && desired.AliasTarget != nil {
+		return fmt.Sprintf("%s instead of %s", describeType(live), describeType(desired))
+	}
+	if *live.Type != *desired.Type {
+		return fmt.Sprintf("type %s instead of %s", *live.Type, *desired.Type)
+	}
+	if live.AliasTarget != nil {
+		if normalizeTarget(*live.AliasTarget.DNSName) != normalizeTarget(*desired.AliasTarget.DNSName) {
+			return fmt.Sprintf("target %s instead of %s", aws.NormalizeName(*live.AliasTarget.DNSName), *desired.AliasTarget.DNSName)
+		}
+		return ""
+	}
+	liveValues, desiredValues := recordValues(live), recordValues(desired)
+	if strings.Join(liveValues, ",") != strings.Join(desiredValues, ",") {
+		return fmt.Sprintf("values %s instead of %s", strings.Join(liveValues, ","), strings.Join(desiredValues, ","))
+	}
+	return ""
+}
+
+// return the type of the record set including whether it is an alias, e.g. ALIAS A
+func describeType(resourceRecordSet *route53.ResourceRecordSet) string {
+	if resourceRecordSet.AliasTarget != nil {
+		return "ALIAS " + *resourceRecordSet.Type
+	}
+	return *resourceRecordSet.Type
+}
+
+// return the sorted and normalized values of the record set
+func recordValues(resourceRecordSet *route53.ResourceRecordSet) []string {
+	var values []string
+	for _, resourceRecord := range resourceRecordSet.ResourceRecords {
+		values = append(values, normalizeTarget(*resourceRecord.Value))
+	}
+	sort.Strings(values)
+	return values
+}
+
+// return the DNS name of a target in lowercase without trailing dot and without the dualstack prefix of load balancers
+func normalizeTarget(dnsName string) string {
+	return strings.TrimPrefix(strings.ToLower(aws.NormalizeName(dnsName)), "dualstack.")
+}
@@ -0,0 +1,153 @@
+package controller
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/service/route53"
+	"github.com/dbsystel/AmazonRoute53-ingress-controller/aws"
+)
+
+func TestAdoptionDecision(t *testing.T) {
+	live := aws.NewResourceRecordSet("app.example.com", route53.RRTypeCname, 300, []string{"lb.example.net"})
+	tests := []struct {
+		name      string
+		live      *route53.ResourceRecordSet
+		ownership *route53.ResourceRecordSet
+		decision  string
+		owner     string
+	}{
+		{"no record set", nil, nil, adoptionManaged, ""},
+		{"no record set but ownership record", nil, ownershipRecordSet("app.example.com", "other", "ingress/default/app"), adoptionManaged, ""},
+		{"no ownership record", live, nil, adoptionAdopt, ""},
+		{"own ownership record", live, ownershipRecordSet("app.example.com", "ci", "ingress/default/app"), adoptionManaged, ""},
+		{"ownership record of another owner", live, ownershipRecordSet("app.example.com", "other", "ingress/default/app"), adoptionConflict, "other"},
+		{"foreign TXT record", live, aws.NewResourceRecordSet(ownershipName("app.example.com"), route53.RRTypeTxt, 300, []string{`"v=spf1 -all"`}), adoptionConflict, "unknown"},
+	}
+	for _, test := range tests {
+		decision, owner := adoptionDecision(test.live, test.ownership, "ci")
+		if decision != test.decision || owner != test.owner {
+			t.Errorf("%s: got %s, %q, want %s, %q", test.name, decision, owner, test.decision, test.owner)
+		}
+	}
+}
+
+func TestRecordSetDifference(t *testing.T) {
+	const lb = "app-123.eu-central-1.elb.amazonaws.com"
+	cname := aws.NewResourceRecordSet("app.example.com", route53.RRTypeCname, 300, []string{lb})
+	alias := aws.NewAliasResourceRecordSet("app.example.com", route53.RRTypeA, lb, "Z215JYRZR1TBD5", false)
+	tests := []struct {
+		name       string
+		live       *route53.ResourceRecordSet
+		desired    *route53.ResourceRecordSet
+		difference string
+	}{
+		{"same cname", cname, cname, ""},
+		{"cname with other ttl and case", aws.NewResourceRecordSet("app.example.com", route53.RRTypeCname, 60, []string{"App-123.eu-central-1.elb.amazonaws.com."}), cname, ""},
+		{"other cname", aws.NewResourceRecordSet("app.example.com", route53.RRTypeCname, 300, []string{"old.example.net"}), cname, "values old.example.net instead of " + lb},
+		{"same alias with dualstack prefix", aws.NewAliasResourceRecordSet("app.example.com", route53.RRTypeA, "dualstack."+lb+".", "Z215JYRZR1TBD5", true), alias, ""},
+		{"other alias", aws.NewAliasResourceRecordSet("app.example.com", route53.RRTypeA, "old.example.net.", "Z1", false), alias, "target old.example.net instead of " + lb},
+		{"alias instead of cname", alias, cname, "ALIAS A instead of CNAME"},
+		{"cname instead of alias", cname, alias, "CNAME instead of ALIAS A"},
+		{"a instead of cname", aws.NewResourceRecordSet("app.example.com", route53.RRTypeA, 300, []string{"192.0.2.1"}), cname, "type A instead of CNAME"},
+		{"values in other order", aws.NewResourceRecordSet("app.example.com", route53.RRTypeA, 300, []string{"192.0.2.2", "192.0.2.1"}), aws.NewResourceRecordSet("app.example.com", route53.RRTypeA, 300, []string{"192.0.2.1", "192.0.2.2"}), ""},
+	}
+	for _, test := range tests {
+		if difference := recordSetDifference(test.live, test.desired); difference != test.difference {
+			t.Errorf("%s: got %q, want %q", test.name, difference, test.difference)
+		}
+	}
+}
+
+func TestChangeRecordSetAdoption(t *testing.T) {
+	const lb = "lb.example.net"
+	tests := []struct {
+		name     string
+		existing []*route53.ResourceRecordSet
+		state    string
+		changed  bool
+		adoption string
+		records  map[string]bool
+	}{
+		{
+			name:    "new record set",
+			state:   route53.ChangeActionUpsert,
+			changed: true,
+			records: map[string]bool{"app.example.com/CNAME": true, "_r53-ingress-owner.app.example.com/TXT": true},
+		},
+		{
+			name:     "matching record set is adopted",
+			existing: []*route53.ResourceRecordSet{aws.NewResourceRecordSet("app.example.com", route53.RRTypeCname, 60, []string{lb})},
+			state:    route53.ChangeActionUpsert,
+			changed:  true,
+			adoption: "adopted",
+			records:  map[string]bool{"app.example.com/CNAME": true, "_r53-ingress-owner.app.example.com/TXT": true},
+		},
+		{
+			name:     "differing record set is kept",
+			existing: []*route53.ResourceRecordSet{aws.NewResourceRecordSet("app.example.com", route53.RRTypeCname, 300, []string{"old.example.net"})},
+			state:    route53.ChangeActionUpsert,
+			adoption: "differs: values old.example.net instead of " + lb,
+			records:  map[string]bool{"app.example.com/CNAME": true},
+		},
+		{
+			name: "record set of another owner is kept",
+			existing: []*route53.ResourceRecordSet{
+				aws.NewResourceRecordSet("app.example.com", route53.RRTypeCname, 300, []string{lb}),
+				ownershipRecordSet("app.example.com", "other", "ingress/default/app"),
+			},
+			state:    route53.ChangeActionUpsert,
+			adoption: "conflict: owned by other",
+			records:  map[string]bool{"app.example.com/CNAME": true, "_r53-ingress-owner.app.example.com/TXT": true},
+		},
+		{
+			name: "record set of another owner is not deleted",
+			existing: []*route53.ResourceRecordSet{
+				aws.NewResourceRecordSet("app.example.com", route53.RRTypeCname, 300, []string{lb}),
+				ownershipRecordSet("app.example.com", "other", "ingress/default/app"),
+			},
+			state:   route53.ChangeActionDelete,
+			records: map[string]bool{"app.example.com/CNAME": true, "_r53-ingress-owner.app.example.com/TXT": true},
+		},
+		{
+			name:     "record set without ownership record is not deleted",
+			existing: []*route53.ResourceRecordSet{aws.NewResourceRecordSet("app.example.com", route53.RRTypeCname, 300, []string{lb})},
+			state:    route53.ChangeActionDelete,
+			records:  map[string]bool{"app.example.com/CNAME": true},
+		},
+		{
+			name: "own record set is deleted",
+			existing: []*route53.ResourceRecordSet{
+				aws.NewResourceRecordSet("app.example.com", route53.RRTypeCname, 300, []string{lb}),
+				ownershipRecordSet("app.example.com", "ci", "ingress/default/app"),
+			},
+			state:   route53.ChangeActionDelete,
+			changed: true,
+			records: map[string]bool{},
+		},
+	}
+	for _, test := range tests {
+		c, zones, cleanup := newZoneFileController(t, "ci")
+		c.EnableAdoption()
+		if len(test.existing) > 0 {
+			writeRecordSets(t, zones, test.existing...)
+		}
+		source := newStatusSource(t, c, "app")
+		c.addReference("app.example.com", source, loadBalancer{dnsName: lb})
+
+		if changed := c.changeRecordSet(test.state, lb, "", "app.example.com", "example.com", "cname", source); changed != test.changed {
+			t.Errorf("%s: got changed %v, want %v", test.name, changed, test.changed)
+		}
+		var adoption string
+		if status := hostStatusOf(t, c, source, "app.example.com"); status != nil {
+			adoption = status.Adoption
+		}
+		if adoption != test.adoption {
+			t.Errorf("%s: got adoption %q, want %q", test.name, adoption, test.adoption)
+		}
+		if records := zoneRecordSets(t, zones); !reflect.DeepEqual(records, test.records) {
+			t.Errorf("%s: got record sets %v, want %v", test.name, records, test.records)
+		}
+		cleanup()
+	}
+}
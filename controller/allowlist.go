@@ -0,0 +1,103 @@
+package controller
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Allowlist decides whether a host may be managed by the controller
+type Allowlist struct {
+	prefixes []string
+	suffixes []string
+	globs    []glob
+	regexes  []*regexp.Regexp
+	denylist []glob
+}
+
+// glob is a host pattern where "*" matches any sequence of characters within a single label and "?" matches a single character
+type glob struct {
+	pattern string
+	re      *regexp.Regexp
+}
+
+// NewAllowlist creates a new object from type Allowlist and return object pointer.
+// globs and denylist are glob patterns, every regex is a single regular expression.
+func NewAllowlist(prefixes []string, suffixes []string, globs []string, regexes []string, denylist []string) (*Allowlist, error) {
+	allowlist := &Allowlist{}
+	allowlist.prefixes = trimList(prefixes)
+	allowlist.suffixes = trimList(suffixes)
+	for _, pattern := range trimList(globs) {
+		allowlist.globs = append(allowlist.globs, compileGlob(pattern))
+	}
+	for _, expr := range regexes {
+		if expr == "" {
+			continue
+		}
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, err
+		}
+		allowlist.regexes = append(allowlist.regexes, re)
+	}
+	for _, pattern := range trimList(denylist) {
+		allowlist.denylist = append(allowlist.denylist, compileGlob(pattern))
+	}
+	return allowlist, nil
+}
+
+// Evaluate returns whether the host is allowed and the rule which led to the decision.
+// The denylist always wins, a host matching no rule is not allowed.
+func (a *Allowlist) Evaluate(host string) (bool, string) {
+	for _, g := range a.denylist {
+		if g.re.MatchString(host) {
+			return false, "denylist:" + g.pattern
+		}
+	}
+	for _, prefix := range a.prefixes {
+		if strings.HasPrefix(host, prefix) {
+			return true, "prefix:" + prefix
+		}
+	}
+	for _, suffix := range a.suffixes {
+		if strings.HasSuffix(host, suffix) {
+			return true, "suffix:" + suffix
+		}
+	}
+	for _, g := range a.globs {
+		if g.re.MatchString(host) {
+			return true, "glob:" + g.pattern
+		}
+	}
+	for _, re := range a.regexes {
+		if re.MatchString(host) {
+			return true, "regex:" + re.String()
+		}
+	}
+	return false, "none"
+}
+
+// split a comma separated list and drop empty entries
+func splitList(list string) []string {
+	return trimList(strings.Split(list, ","))
+}
+
+// trim the entries of a list and drop empty entries
+func trimList(list []string) []string {
+	var entries []string
+	for _, entry := range list {
+		entry = strings.TrimSpace(entry)
+		if entry != "" {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+// compile a glob pattern to an anchored regular expression
+func compileGlob(pattern string) glob {
+	expr := regexp.QuoteMeta(pattern)
+	expr = strings.Replace(expr, `\*`, `[^.]*`, -1)
+	// "?" never matches the "*" label of a wildcard host, which stands for any name
+	expr = strings.Replace(expr, `\?`, `[^.*]`, -1)
+	return glob{pattern: pattern, re: regexp.MustCompile("^" + expr + "$")}
+}
@@ -0,0 +1,93 @@
+package controller
+
+import "testing"
+
+func TestCompileGlob(t *testing.T) {
+	tests := []struct {
+		pattern string
+		host    string
+		match   bool
+	}{
+		{"*.example.com", "app.example.com", true},
+		{"*.example.com", "a.b.example.com", false},
+		{"*.example.com", "example.com", false},
+		{"*.example.com", "app.example.org", false},
+		{"app-*.example.com", "app-1.example.com", true},
+		{"app-*.example.com", "app-.example.com", true},
+		{"app?.example.com", "app1.example.com", true},
+		{"app?.example.com", "app12.example.com", false},
+		{"?.example.com", "*.example.com", false},
+		{"*.example.com", "*.example.com", true},
+		{"app.example.com", "appxexample.com", false},
+		{"app.example.com", "app.example.com", true},
+	}
+	for _, test := range tests {
+		if match := compileGlob(test.pattern).re.MatchString(test.host); match != test.match {
+			t.Errorf("glob %q on %q: got %v, want %v", test.pattern, test.host, match, test.match)
+		}
+	}
+}
+
+func TestAllowlistEvaluate(t *testing.T) {
+	allowlist, err := NewAllowlist(
+		[]string{"internal-", " "},
+		[]string{".example.com"},
+		[]string{"*.example.org"},
+		[]string{`^api[0-9]+\.example\.net$`},
+		[]string{"*.critical.example.com", "internal-db.example.com"},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		host    string
+		allowed bool
+		rule    string
+	}{
+		{"app.example.com", true, "suffix:.example.com"},
+		{"internal-app.example.io", true, "prefix:internal-"},
+		{"app.example.org", true, "glob:*.example.org"},
+		{"a.b.example.org", false, "none"},
+		{"api1.example.net", true, `regex:^api[0-9]+\.example\.net$`},
+		{"api.example.net", false, "none"},
+		{"db.critical.example.com", false, "denylist:*.critical.example.com"},
+		{"internal-db.example.com", false, "denylist:internal-db.example.com"},
+		{"example.io", false, "none"},
+	}
+	for _, test := range tests {
+		allowed, rule := allowlist.Evaluate(test.host)
+		if allowed != test.allowed || rule != test.rule {
+			t.Errorf("Evaluate(%q) = %v, %q, want %v, %q", test.host, allowed, rule, test.allowed, test.rule)
+		}
+	}
+}
+
+func TestNewAllowlistInvalidRegex(t *testing.T) {
+	if _, err := NewAllowlist(nil, nil, nil, []string{"("}, nil); err == nil {
+		t.Error("expected an error for an invalid regular expression")
+	}
+}
+
+func TestSplitList(t *testing.T) {
+	tests := []struct {
+		list    string
+		entries []string
+	}{
+		{"", nil},
+		{"a", []string{"a"}},
+		{" a , ,b,", []string{"a", "b"}},
+	}
+	for _, test := range tests {
+		entries := splitList(test.list)
+		if len(entries) != len(test.entries) {
+			t.Errorf("splitList(%q) = %q, want %q", test.list, entries, test.entries)
+			continue
+		}
+		for i := range entries {
+			if entries[i] != test.entries[i] {
+				t.Errorf("splitList(%q) = %q, want %q", test.list, entries, test.entries)
+			}
+		}
+	}
+}
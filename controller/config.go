@@ -0,0 +1,77 @@
+package controller
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/go-kit/kit/log/level"
+)
+
+// Config holds the settings of the controller which can be changed at runtime
+type Config struct {
+	Allowlist *Allowlist
+	// default record type (alias / cname)
+	DNSType string
+	// TTL of CNAME and ownership records
+	TTL int64
+	// IAM role to assume per hosted zone name
+	ZoneRoles map[string]string
+	// ID written to ownership records, no ownership records are written if empty
+	OwnerID string
+}
+
+// ApplyConfig replaces the config of the running controller, events are not handled while the config is replaced
+// and it is not replaced during background passes. If the record type or TTL changed, the record sets of all
+// referenced hosts are written again afterwards. A changed owner ID is rejected, because the existing ownership
+// records would no longer match and every managed host would be owned by another owner.
+func (c *Controller) ApplyConfig(config Config) error {
+	c.configMutex.Lock()
+	c.mutex.Lock()
+	previous := c.config
+	if previous.OwnerID != config.OwnerID {
+		c.mutex.Unlock()
+		c.configMutex.Unlock()
+		return fmt.Errorf("the owner ID cannot be changed from %q to %q by a reload, the ownership records of the managed hosts carry the current one", previous.OwnerID, config.OwnerID)
+	}
+	c.config = config
+	level.Info(c.logger).Log("msg", "Applied new config", "dnsType", config.DNSType, "ttl", config.TTL, "ownerID", config.OwnerID)
+	hosts := c.sortedHosts()
+	c.mutex.Unlock()
+	c.configMutex.Unlock()
+
+	if previous.DNSType == config.DNSType && previous.TTL == config.TTL {
+		return nil
+	}
+	level.Info(c.logger).Log("msg", "Record settings changed, resyncing all referenced hosts", "hosts", len(hosts))
+	for _, host := range hosts {
+		c.resyncHost(host)
+	}
+	return nil
+}
+
+// write the record set of the host again with the current config, unless it is no longer referenced.
+// Events are handled between the hosts.
+func (c *Controller) resyncHost(host string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	defer c.track("resync")()
+
+	if len(c.hostReferences[host]) == 0 {
+		return
+	}
+	c.reconcileHost(host)
+}
+
+// return the IAM role to assume for the hosted zone of the given host, which is the role of the longest matching zone name
+func (c *Controller) zoneRole(host string) string {
+	roleARN := ""
+	longest := -1
+	for zone, role := range c.config.ZoneRoles {
+		zone = strings.TrimSuffix(zone, ".")
+		if (host == zone || strings.HasSuffix(host, "."+zone)) && len(zone) > longest {
+			roleARN = role
+			longest = len(zone)
+		}
+	}
+	return roleARN
+}
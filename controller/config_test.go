@@ -0,0 +1,41 @@
+package controller
+
+import "testing"
+
+func TestApplyConfig(t *testing.T) {
+	tests := []struct {
+		name     string
+		ttl      int64
+		ownerID  string
+		rejected bool
+		wantTTL  int64
+	}{
+		{"unchanged", 300, "ci", false, 300},
+		{"changed ttl is written to the record sets", 60, "ci", false, 60},
+		{"changed owner ID is rejected", 60, "other", true, 300},
+		{"removed owner ID is rejected", 300, "", true, 300},
+	}
+	for _, test := range tests {
+		c, _, cleanup := newZoneFileController(t, "ci")
+		c.UseStaticLoadBalancers(map[string]string{"lb": "lb-123.eu-central-1.elb.amazonaws.com"})
+		c.Create(newIngress(t, c, "app", "lb", "app.example.com"))
+
+		config := c.config
+		config.TTL = test.ttl
+		config.OwnerID = test.ownerID
+		if err := c.ApplyConfig(config); (err != nil) != test.rejected {
+			t.Errorf("%s: got error %v, want rejected %v", test.name, err, test.rejected)
+		}
+		if test.rejected && c.config.OwnerID != "ci" {
+			t.Errorf("%s: got owner ID %q after the rejected config, want ci", test.name, c.config.OwnerID)
+		}
+		live, err := c.liveRecordSet("app.example.com", "example.com", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if *live.TTL != test.wantTTL {
+			t.Errorf("%s: got ttl %d, want %d", test.name, *live.TTL, test.wantTTL)
+		}
+		cleanup()
+	}
+}
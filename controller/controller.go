@@ -1,111 +1,140 @@
 package controller
 
 import (
-	"encoding/json"
+	"errors"
+	"fmt"
 	"regexp"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/service/route53"
 	"github.com/dbsystel/AmazonRoute53-ingress-controller/aws"
+	"github.com/dbsystel/AmazonRoute53-ingress-controller/metrics"
+	"github.com/dbsystel/AmazonRoute53-ingress-controller/provider"
 	"github.com/go-kit/kit/log"
 	"github.com/go-kit/kit/log/level"
-	"k8s.io/api/networking/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/workqueue"
 )
 
 // Controller defines struct
 type Controller struct {
-	logger               log.Logger
-	allowlistPrefix      string
-	allowlistSuffix      string
-	deleteAlias          bool
-	deleteCname          bool
-	dnsType              string
-	hostReferenceCounter map[string]int
+	logger  log.Logger
+	kclient kubernetes.Interface
+	// client for sources which are custom resources, e.g. HTTP routes
+	dclient  dynamic.Interface
+	recorder record.EventRecorder
+	// DNS service whose record sets are managed, e.g. Amazon Route53
+	provider        provider.Provider
+	config          Config
+	namespacePolicy bool
+	// cached namespaces, required by the namespace policy
+	namespaces corelisters.NamespaceLister
+	// compiled grants by the value of the domains annotation of a namespace
+	grants sync.Map
+	// cached services, Istio gateways and virtual services, required by the Istio sources
+	services        corelisters.ServiceLister
+	istioGateways   cache.GenericLister
+	virtualServices cache.GenericLister
+	// cached Gateway API gateways and HTTP routes, required by the HTTP route source
+	gateways       cache.GenericLister
+	httpRoutes     cache.GenericLister
+	tlsHosts       bool
+	policy         string
+	deletionLimits DeletionLimits
+	// timestamps of record set deletions within the deletion limits window
+	deletions []time.Time
+	// number of managed hosts when the first of the deletions within the window happened
+	managedAtWindowStart int
+	// held back deletions by host or DNSRecord key
+	heldDeletions  map[string]*heldDeletion
+	hostReferences map[string]map[string]*hostReference
+	// record sets declared by DNSRecords, by name/type[/setIdentifier] to the declaring resource
+	records            map[string]string
+	propagationTimeout time.Duration
+	// submitted changes until they are INSYNC by change ID, polled from the changes queue
+	pendingChanges map[string]*pendingChange
+	pendingMutex   sync.Mutex
+	changes        workqueue.RateLimitingInterface
+	// statuses of the resources by resource key, written from the statusQueue
+	statuses    map[string]*sourceStatus
+	statusQueue workqueue.RateLimitingInterface
+	statusMutex sync.Mutex
+	// held while an event is handled or the config is replaced
+	mutex sync.Mutex
+	// read locked by background passes which read the config without mutex, write locked before mutex to replace the config
+	configMutex sync.RWMutex
+	// if true, changes are only logged instead of submitted
+	dryRun bool
+	// if true, existing record sets not owned by the controller are adopted instead of overwritten
+	adoption bool
+	// if true, drifted record sets are corrected instead of only reported
+	fixDrift bool
+	// canonical hosted zone IDs of AWS load balancers by DNS name, which never change
+	hostedZoneIDs sync.Map
+	// cached attributes of load balancers by name, nil if the cache is disabled
+	loadBalancers      map[string]loadBalancerAttributes
+	loadBalancersMutex sync.Mutex
+	// DNS names of load balancers by name, which are never looked up with the AWS API if set
+	staticLoadBalancers map[string]string
+	// start times of the events being handled by operation ID
+	inFlight      map[uint64]time.Time
+	inFlightID    uint64
+	inFlightMutex sync.Mutex
 }
 
 // New creates a new object from type Controller and return object pointer
-func New(logger log.Logger, allowlistPrefix string, allowlistSuffix string, deleteAlilas bool, deleteCname bool, dnsType string) *Controller {
+func New(logger log.Logger, kclient kubernetes.Interface, dnsProvider provider.Provider, config Config, namespacePolicy bool, tlsHosts bool, policy string, deletionLimits DeletionLimits, propagationTimeout time.Duration) *Controller {
 	controller := &Controller{}
 	controller.logger = logger
-	controller.allowlistPrefix = allowlistPrefix
-	controller.allowlistSuffix = allowlistSuffix
-	controller.deleteAlias = deleteAlilas
-	controller.deleteCname = deleteCname
-	controller.dnsType = dnsType
-	controller.hostReferenceCounter = make(map[string]int)
+	controller.kclient = kclient
+	controller.recorder = newEventRecorder(kclient)
+	controller.provider = dnsProvider
+	controller.config = config
+	controller.namespacePolicy = namespacePolicy
+	controller.tlsHosts = tlsHosts
+	controller.policy = policy
+	controller.deletionLimits = deletionLimits
+	controller.heldDeletions = make(map[string]*heldDeletion)
+	controller.hostReferences = make(map[string]map[string]*hostReference)
+	controller.records = make(map[string]string)
+	controller.propagationTimeout = propagationTimeout
+	controller.pendingChanges = make(map[string]*pendingChange)
+	controller.changes = newChangeQueue()
+	controller.statuses = make(map[string]*sourceStatus)
+	controller.statusQueue = newStatusQueue()
+	controller.inFlight = make(map[uint64]time.Time)
 	return controller
 }
 
-// Create will do something when an ingress resource is beeing created
-func (c *Controller) Create(obj interface{}) {
-	level.Debug(c.logger).Log("msg", "Called function: Create")
-	ingressObj := obj.(*v1beta1.Ingress)
-
-	r53, _ := ingressObj.Annotations["ingress.net/route53"]
-
-	isR53, _ := strconv.ParseBool(r53)
-
-	if isR53 {
-		level.Info(c.logger).Log("msg", "Creation of an ingress resource detected", "ingressName", ingressObj.Name, "ingressNamespace", ingressObj.Namespace)
-
-		c.createRecordSet(ingressObj)
-	}
+// UseDynamicClient sets the client for sources which are custom resources, it is required before their events are handled
+func (c *Controller) UseDynamicClient(dclient dynamic.Interface) {
+	c.dclient = dclient
 }
 
-// Update will do something when an ingress resource is beeing updated
-func (c *Controller) Update(oldobj interface{}, newobj interface{}) {
-	newIngressObj := newobj.(*v1beta1.Ingress)
-	oldIngressObj := oldobj.(*v1beta1.Ingress)
-
-	level.Debug(c.logger).Log("msg", "Called function: Update")
-
-	if c.noDifference(oldIngressObj, newIngressObj) {
-		level.Debug(c.logger).Log("msg", "Skipping automatically updated ingress", "ingressName", newIngressObj.Name, "ingressNamespace", newIngressObj.Namespace)
-		return
-	}
-
-	oldR53, _ := oldIngressObj.Annotations["ingress.net/route53"]
-	r53, _ := newIngressObj.Annotations["ingress.net/route53"]
-
-	isOldR53, _ := strconv.ParseBool(oldR53)
-	isR53, _ := strconv.ParseBool(r53)
-
-	if isOldR53 {
-		level.Info(c.logger).Log("msg", "Update of an ingress resource detected, the old one will be deleted.", "ingressName", oldIngressObj.Name, "ingressNamespace", oldIngressObj.Namespace)
-
-		c.deleteRecordSet(oldIngressObj)
-	}
-
-	if isR53 {
-		level.Info(c.logger).Log("msg", "Update of an ingress resource detected, the new one will be created.", "ingressName", newIngressObj.Name, "ingressNamespace", newIngressObj.Namespace)
-
-		c.createRecordSet(newIngressObj)
-	}
+// EnableDryRun makes the controller log its changes instead of submitting them
+func (c *Controller) EnableDryRun() {
+	c.dryRun = true
 }
 
-// Delete will do something when an ingress resource is beeing deleted
-func (c *Controller) Delete(obj interface{}) {
-	level.Debug(c.logger).Log("msg", "Called function: Delete")
-	ingressObj := obj.(*v1beta1.Ingress)
-
-	r53, _ := ingressObj.Annotations["ingress.net/route53"]
-
-	isR53, _ := strconv.ParseBool(r53)
-
-	if isR53 {
-		level.Info(c.logger).Log("msg", "Deletion of an ingress resource detected", "ingressName", ingressObj.Name, "ingressNamespace", ingressObj.Namespace)
-
-		c.deleteRecordSet(ingressObj)
+func (c *Controller) searchHostedZone(host string) provider.Zone {
+	level.Debug(c.logger).Log("msg", "Searching Hosted Zone ID for provided host ", "host", host, "roleARN", c.zoneRole(host))
+	zones, err := c.provider.Zones(c.zoneRole(host))
+	hostedZone, found := provider.FindZone(zones, host)
+	if err == nil && !found {
+		err = errors.New("Hosted Zone ID for provided string: " + host + ". not found!")
 	}
-}
-
-func (c *Controller) searchHostedZoneID(host string) string {
-
-	hostedZoneID, err := aws.GetHostedZone(host, c.logger)
 
 	if err != nil {
+		countAWSAPIError(err)
 		if aerr, ok := err.(awserr.Error); ok {
 			switch aerr.Code() {
 			case route53.ErrCodeInvalidInput:
@@ -124,186 +153,345 @@ func (c *Controller) searchHostedZoneID(host string) string {
 		}
 	}
 
-	return hostedZoneID
+	return hostedZone
 }
 
-// retrun dnsName and hostedZoneNameID for give load-balancer-name
-func (c *Controller) getLoadBalancerAttributes(loadBalancername string) (string, string) {
-
-	dnsName, hostedZoneNameID := aws.GetELBAttributes(loadBalancername, c.logger)
-	if dnsName == "" {
-		dnsName, hostedZoneNameID = aws.GetALBAttributes(loadBalancername, c.logger)
-	}
-	return dnsName, hostedZoneNameID
+// check if given host is in allowlist before its record set is deleted, the decision is only logged.
+// Hosts are not checked against the namespace policy on deletion, they were granted when they were referenced.
+func (c *Controller) isInAllowlist(host string, sourceObj recordSource) bool {
+	allowed, rule := c.config.Allowlist.Evaluate(host)
+	level.Debug(c.logger).Log("msg", "Evaluated allowlist", "hostName", host, "allowed", allowed, "rule", rule, "resource", sourceKey(sourceObj))
+	return allowed
 }
 
-// are the two ingress resources same?
-func (c *Controller) noDifference(newIngressObj *v1beta1.Ingress, oldIngressObj *v1beta1.Ingress) bool {
-	if len(newIngressObj.Spec.Rules) != len(oldIngressObj.Spec.Rules) {
-		newIngressObjContent, _ := json.Marshal(newIngressObj.Spec.Rules)
-		oldIngressObjContent, _ := json.Marshal(oldIngressObj.Spec.Rules)
-		level.Debug(c.logger).Log(
-			"msg", "length of ingressObj spec rules are different",
-			"newIngressObjSpecRulesLength", len(newIngressObj.Spec.Rules),
-			"oldIngressObjSpecRulesLength", len(oldIngressObj.Spec.Rules),
-			"newIngressObjSpecRulesContent", string(newIngressObjContent),
-			"oldIngressObjSpecRulesContent", string(oldIngressObjContent),
-		)
+// log the decision about the host of the resource before its record set is created or updated and report it as events,
+// returns true if the record set may be written
+func (c *Controller) reportHostDecision(host string, decision hostDecision, sourceObj recordSource) bool {
+	level.Debug(c.logger).Log("msg", "Evaluated allowlist", "hostName", host, "allowed", decision.allowed, "rule", decision.rule, "resource", sourceKey(sourceObj))
+	if !decision.allowed {
+		c.recorder.Eventf(sourceObj, corev1.EventTypeWarning, "HostNotAllowed", "Host %s is not allowed (rule %s), no Route53 record set will be created", host, decision.rule)
 		return false
 	}
-	for i, ingressRule := range newIngressObj.Spec.Rules {
-		if ingressRule.Host != oldIngressObj.Spec.Rules[i].Host {
-			level.Debug(c.logger).Log(
-				"msg", "ingressObj spec rules host names are different",
-				"newIngressObjHostName", ingressRule.Host,
-				"oldIngressObjHostName", oldIngressObj.Spec.Rules[i].Host,
-			)
-			return false
-		}
+	c.recorder.Eventf(sourceObj, corev1.EventTypeNormal, "HostAllowed", "Host %s is allowed by rule %s", host, decision.rule)
+
+	switch {
+	case decision.err != nil:
+		level.Error(c.logger).Log("msg", "Could not get namespace to check domain delegation", "err", decision.err.Error(), "hostName", host, "resource", sourceKey(sourceObj))
+	case !decision.delegated:
+		level.Info(c.logger).Log("msg", "Provided host "+host+" is not delegated to namespace "+sourceObj.GetNamespace()+". Skipping!", "hostName", host, "resource", sourceKey(sourceObj))
+		c.recorder.Eventf(sourceObj, corev1.EventTypeWarning, "HostNotDelegated", "Host %s is not granted to namespace %s by its annotation %s, no Route53 record set will be created", host, sourceObj.GetNamespace(), domainsAnnotation)
+	case decision.grant != "":
+		level.Debug(c.logger).Log("msg", "Host is delegated to namespace", "hostName", host, "grant", decision.grant, "resource", sourceKey(sourceObj))
 	}
+	return decision.delegated
+}
 
-	if newIngressObj.Annotations["ingress.net/route53"] != oldIngressObj.Annotations["ingress.net/route53"] {
-		level.Debug(c.logger).Log(
-			"msg", "ingressObj annotations route53 are different",
-			"newIngressObjAnnotation", newIngressObj.Annotations["ingress.net/route53"],
-			"oldIngressObjAnnotation", oldIngressObj.Annotations["ingress.net/route53"],
-		)
-		return false
-	}
+// delete Amazon Route53 recordset of the given hosts of the resource, pointing to the target they were published for
+func (c *Controller) deleteRecordSet(sourceObj recordSource, hosts []string) {
+	for _, host := range hosts {
+		level.Info(c.logger).Log("msg", "Deleting Route53 record set", "hostName", host, "resource", sourceKey(sourceObj))
+		if c.isInAllowlist(host, sourceObj) {
+			reference, ok := c.hostReferences[host][sourceKey(sourceObj)]
+			if !ok {
+				level.Info(c.logger).Log("msg", "The hostname "+host+" is not referenced by this resource. Deletion Skipped.", "hostName", host, "resource", sourceKey(sourceObj))
+				continue
+			}
+			// the record set was published for the referenced target, which may differ from the current one of the resource
+			target, published := reference.target, reference.published
+			wasOwner := c.owner(host).source.GetUID() == sourceObj.GetUID()
+			if remaining := c.removeReference(host, sourceObj); remaining > 0 {
+				level.Info(c.logger).Log("msg", "The hostname "+host+" still has "+strconv.Itoa(remaining)+" copies in the k8s-cluster. Deletion Skipped.")
+				if wasOwner {
+					c.handOver(host, target)
+				}
+				c.reportConflicts(host)
+				continue
+			}
 
-	if newIngressObj.Annotations["ingress.net/load-balancer-name"] != oldIngressObj.Annotations["ingress.net/load-balancer-name"] {
-		level.Debug(c.logger).Log(
-			"msg", "ingressObj annotations load-balancer-name are different",
-			"newIngressObjAnnotation", newIngressObj.Annotations["ingress.net/load-balancer-name"],
-			"oldIngressObjAnnotation", oldIngressObj.Annotations["ingress.net/load-balancer-name"],
-		)
-		return false
-	}
+			host := host
+			c.guardDeletion(host, sourceObj, func() {
+				hostedZone := c.searchHostedZone(host)
+				level.Debug(c.logger).Log("msg", "Found Hosted Zone ID: ", "hostedzoneid", hostedZone.ID)
 
-	if newIngressObj.Annotations["ingress.net/alias"] != oldIngressObj.Annotations["ingress.net/alias"] {
-		level.Debug(c.logger).Log(
-			"msg", "ingressObj annotations alias are different",
-			"newIngressObjAnnotation", newIngressObj.Annotations["ingress.net/alias"],
-			"oldIngressObjAnnotation", oldIngressObj.Annotations["ingress.net/alias"],
-		)
-		return false
+				aliasName, aliasHostedZoneID := published, ""
+				if aliasName == "" {
+					aliasName, aliasHostedZoneID = c.getLoadBalancerAttributes(target)
+				}
+				level.Debug(c.logger).Log("aliasName: ", aliasName, "aliasHostedZoneID: ", aliasHostedZoneID)
+				c.changeRecordSet("DELETE", aliasName, aliasHostedZoneID, host, hostedZone.ID, c.config.DNSType, sourceObj)
+			})
+		} else {
+			metrics.HostsSkipped.WithLabelValues("delete").Inc()
+			level.Info(c.logger).Log("msg", "Provided host "+host+" is not in allowlist. Skipping deletion!", "hostName", host, "resource", sourceKey(sourceObj))
+		}
 	}
 
-	return true
 }
 
-// check if gice host is in allowlist
-func (c *Controller) isInAllowlist(host string) (inAllowlist bool) {
-	if c.allowlistPrefix != "" {
-		prefixes := strings.Split(c.allowlistPrefix, ",")
-		for _, prefix := range prefixes {
-			if prefix == "" {
-				continue
-			}
-			inAllowlist = strings.HasPrefix(host, prefix)
-			if inAllowlist {
-				break
+// create Amazon Route53 recordset of the given hosts of the resource pointing to the target
+func (c *Controller) createRecordSet(sourceObj recordSource, hosts []string, target loadBalancer) {
+	for _, host := range hosts {
+		level.Info(c.logger).Log("msg", "Creating/Updating Route53 record set", "hostName", host, "resource", sourceKey(sourceObj))
+		decision := c.decideHost(host, sourceObj)
+		if !c.reportHostDecision(host, decision, sourceObj) {
+			if !decision.allowed {
+				metrics.HostsSkipped.WithLabelValues("create").Inc()
+				level.Info(c.logger).Log("msg", "Provided host "+host+" is not in allowlist. Skipping creation/updating!", "hostName", host, "resource", sourceKey(sourceObj))
+			} else {
+				metrics.HostsNotDelegated.WithLabelValues("create").Inc()
 			}
+			continue
+		}
 
+		if claimant := c.hostRecordClaimant(host); claimant != "" {
+			level.Warn(c.logger).Log("msg", "The hostname "+host+" is declared by "+claimant+". Skipping creation/updating!", "hostName", host, "resource", sourceKey(sourceObj))
+			c.recorder.Eventf(sourceObj, corev1.EventTypeWarning, "HostConflict", "Host %s is declared by %s, no Route53 record set will be created", host, claimant)
+			continue
 		}
-	}
-	if c.allowlistSuffix != "" {
-		suffixes := strings.Split(c.allowlistSuffix, ",")
-		for _, suffix := range suffixes {
-			if suffix == "" {
-				continue
-			}
-			if inAllowlist {
-				break
-			}
-			inAllowlist = strings.HasSuffix(host, suffix)
+
+		reference := c.addReference(host, sourceObj, target)
+		c.releaseHeldDeletion(host)
+		c.reportConflicts(host)
+		if owner := c.owner(host); owner.target != target {
+			level.Warn(c.logger).Log("msg", "The hostname "+host+" is owned by "+sourceKey(owner.source)+" pointing to another load balancer. Skipping creation/updating!", "hostName", host, "resource", sourceKey(sourceObj))
+			continue
 		}
-	}
-	return inAllowlist
-}
 
-// delete Amazon Route53 recordset
-func (c *Controller) deleteRecordSet(ingressObj *v1beta1.Ingress) {
-	loadBalancerName, _ := ingressObj.Annotations["ingress.net/load-balancer-name"]
+		hostedZone := c.searchHostedZone(host)
+		level.Debug(c.logger).Log("msg", "Found Hosted Zone ID: ", "hostedzoneid", hostedZone.ID)
 
-	for _, ingressRule := range ingressObj.Spec.Rules {
-		level.Info(c.logger).Log("msg", "Deleting Route53 record set", "hostName", ingressRule.Host, "ingressName", ingressRule.Host, "ingressNamespace", ingressObj.Namespace)
-		if c.isInAllowlist(ingressRule.Host) {
-			c.hostReferenceCounter[ingressRule.Host]--
-			if c.hostReferenceCounter[ingressRule.Host] > 0 {
-				level.Info(c.logger).Log("msg", "The hostname "+ingressRule.Host+" still has "+strconv.Itoa(c.hostReferenceCounter[ingressRule.Host])+" copies in the k8s-cluster. Deletion Skipped.")
-				continue
-			}
-			hostedZoneID := c.searchHostedZoneID(ingressRule.Host)
-			level.Debug(c.logger).Log("msg", "Found Hosted Zone ID: ", "hostedzoneid", hostedZoneID)
+		aliasName, aliasHostedZoneID := c.getLoadBalancerAttributes(target)
+		level.Debug(c.logger).Log("aliasName: ", aliasName, "aliasHostedZoneID: ", aliasHostedZoneID)
+		if aliasName == "" {
+			c.reportLoadBalancerNotFound(host, target, sourceObj)
+			continue
+		}
 
-			aliasName, aliasHostedZoneID := c.getLoadBalancerAttributes(loadBalancerName)
-			level.Debug(c.logger).Log("aliasName: ", aliasName, "aliasHostedZoneID: ", aliasHostedZoneID)
-			result, err := aws.ChangeRecordSet("DELETE", aliasName, aliasHostedZoneID, ingressRule.Host, hostedZoneID, c.dnsType)
+		dnsType, ok := c.dnsType(host, hostedZone, aliasHostedZoneID, sourceObj)
+		if !ok {
+			continue
+		}
+		if c.changeRecordSet("UPSERT", aliasName, aliasHostedZoneID, host, hostedZone.ID, dnsType, sourceObj) {
+			reference.published = aliasName
+		}
+	}
+}
 
-			if err != nil {
-				c.handleError(err)
-			} else {
-				level.Info(c.logger).Log("msg", result, "hostName", ingressRule.Host, "ingressName", ingressObj.Name, "ingressNamespace", ingressObj.Namespace)
-			}
+// point the record set of the host to the load balancer of its new owner after the previous owner was deleted
+func (c *Controller) handOver(host string, previousTarget loadBalancer) {
+	owner := c.owner(host)
+	if owner.target == previousTarget {
+		return
+	}
+	level.Info(c.logger).Log("msg", "The hostname "+host+" is handed over to "+sourceKey(owner.source)+".", "hostName", host, "target", owner.target.String())
+	c.reconcileHost(host)
+}
+
+// point the record set of the referenced host to the load balancer of its owner, returns true if a change was submitted
+func (c *Controller) reconcileHost(host string) bool {
+	if allowed, _ := c.config.Allowlist.Evaluate(host); !allowed {
+		return false
+	}
+	owner := c.owner(host)
+	hostedZone := c.searchHostedZone(host)
+	if hostedZone.ID == "" {
+		return false
+	}
+	aliasName, aliasHostedZoneID := c.getLoadBalancerAttributes(owner.target)
+	if aliasName == "" {
+		c.reportLoadBalancerNotFound(host, owner.target, owner.source)
+		return false
+	}
+	dnsType, ok := c.dnsType(host, hostedZone, aliasHostedZoneID, owner.source)
+	if !ok {
+		return false
+	}
+	if !c.changeRecordSet(route53.ChangeActionUpsert, aliasName, aliasHostedZoneID, host, hostedZone.ID, dnsType, owner.source) {
+		return false
+	}
+	owner.published = aliasName
+	return true
+}
 
+// report resources whose reference to the host started or stopped conflicting with its owner
+func (c *Controller) reportConflicts(host string) {
+	owner := c.owner(host)
+	for _, reference := range c.hostReferences[host] {
+		conflicting := reference.target != owner.target
+		if conflicting == reference.conflicting {
+			continue
+		}
+		reference.conflicting = conflicting
+
+		if conflicting {
+			message := fmt.Sprintf("Host %s is owned by the older resource %s pointing to load balancer %q, this resource is ignored for it", host, sourceKey(owner.source), owner.target)
+			c.recorder.Event(reference.source, corev1.EventTypeWarning, "HostConflict", message)
+			c.setHostStatus(reference.source, host, func(status *hostStatus) {
+				status.Conflict = message
+			})
 		} else {
-			level.Info(c.logger).Log("msg", "Provided host "+ingressRule.Host+" is not in allowlist. Skipping deletion!", "hostName", ingressRule.Host, "ingressName", ingressObj.Name, "ingressNamespace", ingressObj.Namespace)
+			c.recorder.Eventf(reference.source, corev1.EventTypeNormal, "HostConflictResolved", "Host %s is no longer in conflict", host)
+			c.setHostStatus(reference.source, host, func(status *hostStatus) {
+				status.Conflict = ""
+			})
 		}
 	}
-
 }
 
-// create Amazon Route53 recordset
-func (c *Controller) createRecordSet(ingressObj *v1beta1.Ingress) {
-	loadBalancerName, _ := ingressObj.Annotations["ingress.net/load-balancer-name"]
+// submit the change of the record set of the host together with its ownership record in one batch, log its result and record it in the metrics.
+// If the live record set has another type than the desired one, it is deleted in the same batch. Returns true if a change was submitted.
+func (c *Controller) changeRecordSet(state, aliasName, aliasHostedZoneID, host, hostedZoneID, dnsType string, sourceObj recordSource) bool {
+	roleARN := c.zoneRole(host)
+	live, err := c.liveRecordSet(host, hostedZoneID, roleARN)
+	if err != nil {
+		c.handleError(err)
+		return false
+	}
+	adoption, owner, err := c.decideAdoption(host, live, hostedZoneID, roleARN)
+	if err != nil {
+		c.handleError(err)
+		return false
+	}
+	switch {
+	case adoption == adoptionConflict && state == route53.ChangeActionDelete:
+		level.Info(c.logger).Log("msg", "Existing Route53 record set is owned by another owner, deletion skipped", "owner", owner, "hostName", host, "resource", sourceKey(sourceObj))
+		return false
+	case adoption == adoptionConflict:
+		c.reportOwnershipConflict(host, owner, sourceObj)
+		c.setHostStatus(sourceObj, host, func(status *hostStatus) {
+			status.Adoption = "conflict: owned by " + owner
+		})
+		return false
+	case adoption == adoptionAdopt:
+		if state == route53.ChangeActionDelete {
+			level.Info(c.logger).Log("msg", "Existing Route53 record set was not adopted, deletion skipped", "hostName", host, "resource", sourceKey(sourceObj))
+			return false
+		}
+		desired := aws.NewChange(state, aliasName, aliasHostedZoneID, host, dnsType, c.config.TTL).ResourceRecordSet
+		difference, err := c.adopt(host, live, desired, hostedZoneID, roleARN, sourceObj)
+		if err != nil {
+			return false
+		}
+		c.setHostStatus(sourceObj, host, func(status *hostStatus) {
+			status.Adoption = "adopted"
+			if difference != "" {
+				status.Adoption = "differs: " + difference
+			}
+		})
+		return difference == ""
+	}
+	if allowed, reason := c.allowedByPolicy(state, host, live, hostedZoneID, roleARN, sourceObj); !allowed {
+		metrics.PolicySkipped.WithLabelValues(c.policy, state).Inc()
+		level.Info(c.logger).Log("msg", "Route53 change skipped by policy", "policy", c.policy, "reason", reason, "action", state, "hostName", host, "resource", sourceKey(sourceObj))
+		return false
+	}
 
-	for _, ingressRule := range ingressObj.Spec.Rules {
-		level.Info(c.logger).Log("msg", "Creating/Updating Route53 record set", "hostName", ingressRule.Host, "ingressName", ingressObj.Name, "ingressNamespace", ingressObj.Namespace)
-		if c.isInAllowlist(ingressRule.Host) {
+	var changes []*route53.Change
+	switch {
+	case state == route53.ChangeActionDelete && live == nil:
+		level.Info(c.logger).Log("msg", "Route53 record set does not exist, nothing to delete", "hostName", host, "resource", sourceKey(sourceObj))
+	case state == route53.ChangeActionDelete && !pointsTo(live, aliasName) && !c.isOwned(host, hostedZoneID, roleARN):
+		level.Warn(c.logger).Log("msg", "Route53 record set points to another target and is not owned by this controller, deletion skipped", "target", aliasName, "live", describeTarget(live), "hostName", host, "resource", sourceKey(sourceObj))
+		return false
+	case state == route53.ChangeActionDelete:
+		// a DELETE has to match the live record set exactly
+		changes = append(changes, aws.NewRecordSetChange(route53.ChangeActionDelete, live))
+	case live != nil && *live.Type != recordType(dnsType):
+		level.Info(c.logger).Log("msg", "Migrating Route53 record set type", "from", *live.Type, "to", recordType(dnsType), "hostName", host, "resource", sourceKey(sourceObj))
+		changes = append(changes, aws.NewRecordSetChange(route53.ChangeActionDelete, live))
+		changes = append(changes, c.upsertChanges(host, aliasName, aliasHostedZoneID, dnsType, sourceObj)...)
+	default:
+		changes = c.upsertChanges(host, aliasName, aliasHostedZoneID, dnsType, sourceObj)
+	}
+	if state == route53.ChangeActionDelete {
+		if ownership := c.ownershipDeletion(host, hostedZoneID, roleARN); ownership != nil {
+			changes = append(changes, ownership)
+		}
+	}
+	if len(changes) == 0 {
+		return false
+	}
 
-			hostedZoneID := c.searchHostedZoneID(ingressRule.Host)
-			level.Debug(c.logger).Log("msg", "Found Hosted Zone ID: ", "hostedzoneid", hostedZoneID)
+	changeID, err := c.submitChanges(hostedZoneID, roleARN, changes)
+	if err != nil {
+		return false
+	}
+	level.Info(c.logger).Log("msg", "Submitted Route53 change", "action", state, "changeID", changeID, "hostName", host, "resource", sourceKey(sourceObj))
+	c.trackChange(changeID, roleARN, state, host, sourceObj)
+	return true
+}
 
-			aliasName, aliasHostedZoneID := c.getLoadBalancerAttributes(loadBalancerName)
-			level.Debug(c.logger).Log("aliasName: ", aliasName, "aliasHostedZoneID: ", aliasHostedZoneID)
+// check if the live A or CNAME record set points to the given DNS name, either as alias or as CNAME
+func pointsTo(live *route53.ResourceRecordSet, dnsName string) bool {
+	if dnsName == "" {
+		return false
+	}
+	return describeTarget(live) == normalizeTarget(dnsName)
+}
 
-			c.hostReferenceCounter[ingressRule.Host]++
+// return the normalized DNS name the live alias or CNAME record set points to, or its values if it is neither
+func describeTarget(live *route53.ResourceRecordSet) string {
+	if live.AliasTarget != nil {
+		return normalizeTarget(*live.AliasTarget.DNSName)
+	}
+	return strings.Join(recordValues(live), ",")
+}
 
-			//-TODO: DEPRECATE
-			if c.deleteAlias && strings.ToUpper(c.dnsType) != "ALIAS" {
-				level.Info(c.logger).Log("msg", "delete alias recordset before creating cname.")
-				result, err := aws.ChangeRecordSet("DELETE", aliasName, aliasHostedZoneID, ingressRule.Host, hostedZoneID, "ALIAS")
-				if err != nil {
-					c.handleError(err)
-				} else {
-					level.Info(c.logger).Log("msg", result, "hostName", ingressRule.Host, "ingressName", ingressObj.Name, "ingressNamespace", ingressObj.Namespace)
-				}
+// submit the changes as one batch to the hosted zone and record them in the metrics, errors are logged and returned
+func (c *Controller) submitChanges(hostedZoneID, roleARN string, changes []*route53.Change) (string, error) {
+	if c.dryRun {
+		for _, change := range changes {
+			level.Info(c.logger).Log("msg", "Dry run: Route53 change not submitted", "action", *change.Action, "type", *change.ResourceRecordSet.Type, "name", *change.ResourceRecordSet.Name, "hostedZoneID", hostedZoneID)
+		}
+		return dryRunChangeID, nil
+	}
+	changeID, err := c.provider.ApplyChanges(hostedZoneID, roleARN, changes)
+	result := "success"
+	if err != nil {
+		result = "error"
+	}
+	for _, change := range changes {
+		metrics.Route53Changes.WithLabelValues(*change.Action, *change.ResourceRecordSet.Type, result).Inc()
+	}
+	if err != nil {
+		c.handleError(err)
+		return "", err
+	}
 
-			} else if c.deleteCname && strings.ToUpper(c.dnsType) != "CNAME" {
-				level.Info(c.logger).Log("msg", "delete cname recordset before creating alias.")
-				result, err := aws.ChangeRecordSet("DELETE", aliasName, aliasHostedZoneID, ingressRule.Host, hostedZoneID, "CNAME")
-				if err != nil {
-					c.handleError(err)
-				} else {
-					level.Info(c.logger).Log("msg", result, "hostName", ingressRule.Host, "ingressName", ingressObj.Name, "ingressNamespace", ingressObj.Namespace)
-				}
-			}
+	metrics.LastSuccessfulSync.SetToCurrentTime()
+	return changeID, nil
+}
 
-			result, err := aws.ChangeRecordSet("UPSERT", aliasName, aliasHostedZoneID, ingressRule.Host, hostedZoneID, c.dnsType)
+// return the live A or CNAME record set of the host, or nil if it does not exist
+func (c *Controller) liveRecordSet(host, hostedZoneID, roleARN string) (*route53.ResourceRecordSet, error) {
+	resourceRecordSets, err := c.provider.RecordSets(hostedZoneID, roleARN, host)
+	if err != nil {
+		return nil, err
+	}
+	for _, resourceRecordSet := range resourceRecordSets {
+		if *resourceRecordSet.Type == route53.RRTypeA || *resourceRecordSet.Type == route53.RRTypeCname {
+			return resourceRecordSet, nil
+		}
+	}
+	return nil, nil
+}
 
-			if err != nil {
-				c.handleError(err)
-			} else {
-				level.Info(c.logger).Log("msg", result, "hostName", ingressRule.Host, "ingressName", ingressObj.Name, "ingressNamespace", ingressObj.Namespace)
-			}
-		} else {
-			level.Info(c.logger).Log("msg", "Provided host "+ingressRule.Host+" is not in allowlist. Skipping creation/updating!", "hostName", ingressRule.Host, "ingressName", ingressObj.Name, "ingressNamespace", ingressObj.Namespace)
+// return the live record set of the name with given type, or nil if it does not exist
+func (c *Controller) liveRecordSetOfType(name, recordType, hostedZoneID, roleARN string) (*route53.ResourceRecordSet, error) {
+	resourceRecordSets, err := c.provider.RecordSets(hostedZoneID, roleARN, name)
+	if err != nil {
+		return nil, err
+	}
+	for _, resourceRecordSet := range resourceRecordSets {
+		if *resourceRecordSet.Type == recordType {
+			return resourceRecordSet, nil
 		}
 	}
+	return nil, nil
 }
 
 func (c *Controller) handleError(err error) {
+	countAWSAPIError(err)
 	if aerr, ok := err.(awserr.Error); ok {
 		switch aerr.Code() {
 		case route53.ErrCodeNoSuchHostedZone:
@@ -320,7 +508,7 @@ func (c *Controller) handleError(err error) {
 		case route53.ErrCodeInvalidInput:
 			level.Error(c.logger).Log("err", route53.ErrCodeInvalidInput, "msg", aerr.Error())
 		case route53.ErrCodePriorRequestNotComplete:
-			level.Error(c.logger).Log("err", route53.ErrCodeInvalidInput, "msg", aerr.Error())
+			level.Error(c.logger).Log("err", route53.ErrCodePriorRequestNotComplete, "msg", aerr.Error())
 		default:
 			level.Error(c.logger).Log("msg", aerr.Error())
 		}
@@ -330,3 +518,96 @@ func (c *Controller) handleError(err error) {
 		level.Error(c.logger).Log("msg", err.Error())
 	}
 }
+
+// return the Amazon Route53 record type for the given dns type
+func recordType(dnsType string) string {
+	if strings.ToUpper(dnsType) == "ALIAS" {
+		return route53.RRTypeA
+	}
+	return route53.RRTypeCname
+}
+
+// return the record set type for the host. Route53 does not allow a CNAME at the zone apex,
+// so apex hosts pointing to an AWS load balancer get an ALIAS record instead. Returns false for apex hosts of
+// providers without ALIAS records, which cannot be published at all.
+func (c *Controller) dnsType(host string, hostedZone provider.Zone, aliasHostedZoneID string, sourceObj recordSource) (string, bool) {
+	dnsType, ok := c.desiredDNSType(host, hostedZone, aliasHostedZoneID)
+	switch {
+	case !ok:
+		level.Error(c.logger).Log("msg", "The hostname "+host+" is the zone apex, but the provider supports neither CNAME nor ALIAS records there. Skipping creation/updating!", "hostName", host, "resource", sourceKey(sourceObj))
+		c.recorder.Eventf(sourceObj, corev1.EventTypeWarning, "ApexNotSupported", "Host %s is the apex of zone %s where CNAME records are not allowed and the provider has no ALIAS records, no record set will be created", host, hostedZone.Name)
+	case recordType(c.config.DNSType) != route53.RRTypeCname && !c.provider.AliasRecords():
+		level.Warn(c.logger).Log("msg", "ALIAS records are not supported by the provider, using a CNAME record instead", "hostName", host, "resource", sourceKey(sourceObj))
+	case recordType(c.config.DNSType) != route53.RRTypeCname || host != hostedZone.Name:
+	case recordType(dnsType) == route53.RRTypeCname:
+		level.Warn(c.logger).Log("msg", "The hostname "+host+" is the zone apex, but its target is no AWS load balancer. A CNAME record is not allowed there!", "hostName", host, "resource", sourceKey(sourceObj))
+	default:
+		level.Info(c.logger).Log("msg", "The hostname "+host+" is the zone apex, using an ALIAS record instead of a CNAME record", "hostName", host, "resource", sourceKey(sourceObj))
+		c.recorder.Eventf(sourceObj, corev1.EventTypeNormal, "ApexAlias", "Host %s is the apex of hosted zone %s where CNAME records are not allowed, an ALIAS record is used instead", host, hostedZone.Name)
+	}
+	return dnsType, ok
+}
+
+// return the record set type for the host like dnsType, but without logging and reporting the decision
+func (c *Controller) desiredDNSType(host string, hostedZone provider.Zone, aliasHostedZoneID string) (string, bool) {
+	if !c.provider.AliasRecords() && host == hostedZone.Name {
+		return "", false
+	}
+	if recordType(c.config.DNSType) != route53.RRTypeCname && !c.provider.AliasRecords() {
+		return "cname", true
+	}
+	if recordType(c.config.DNSType) != route53.RRTypeCname || host != hostedZone.Name {
+		return c.config.DNSType, true
+	}
+	if aliasHostedZoneID == "" {
+		return c.config.DNSType, true
+	}
+	return "ALIAS", true
+}
+
+// count an AWS API error by its error code
+func countAWSAPIError(err error) {
+	code := "Unknown"
+	if aerr, ok := err.(awserr.Error); ok {
+		code = aerr.Code()
+	}
+	metrics.AWSAPIErrors.WithLabelValues(code).Inc()
+}
+
+// mark an event as being handled until the returned function is called and record how long handling it took.
+// It is called after the mutex is acquired, so waiting for another handler does not count.
+func (c *Controller) track(operation string) func() {
+	c.inFlightMutex.Lock()
+	c.inFlightID++
+	id := c.inFlightID
+	c.inFlight[id] = time.Now()
+	c.inFlightMutex.Unlock()
+	done := observe(operation)
+	return func() {
+		c.inFlightMutex.Lock()
+		delete(c.inFlight, id)
+		c.inFlightMutex.Unlock()
+		done()
+	}
+}
+
+// observe the duration of the operation when the returned function is called. Background passes are only observed,
+// they wait for event handlers and must not count for the liveness probe.
+func observe(operation string) func() {
+	start := time.Now()
+	return func() {
+		metrics.ReconcileDuration.WithLabelValues(operation).Observe(time.Since(start).Seconds())
+	}
+}
+
+// Stalled returns true if handling any event in progress takes longer than timeout
+func (c *Controller) Stalled(timeout time.Duration) bool {
+	c.inFlightMutex.Lock()
+	defer c.inFlightMutex.Unlock()
+	for _, start := range c.inFlight {
+		if time.Since(start) > timeout {
+			return true
+		}
+	}
+	return false
+}
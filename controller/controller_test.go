@@ -0,0 +1,330 @@
+package controller
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/route53"
+	"github.com/dbsystel/AmazonRoute53-ingress-controller/aws"
+	"github.com/dbsystel/AmazonRoute53-ingress-controller/provider"
+	"github.com/dbsystel/AmazonRoute53-ingress-controller/zonefile"
+	"github.com/go-kit/kit/log"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/record"
+)
+
+// create a controller managing the zone example.com in a zone file of a temporary directory, which is removed by cleanup
+func newZoneFileController(t *testing.T, ownerID string) (c *Controller, zones provider.Provider, cleanup func()) {
+	directory, err := ioutil.TempDir("", "controller")
+	if err != nil {
+		t.Fatal(err)
+	}
+	zones, err = zonefile.New(zonefile.Config{Directory: directory, Zones: []string{"example.com"}, TTL: 300})
+	if err != nil {
+		t.Fatal(err)
+	}
+	allowlist, err := NewAllowlist(nil, []string{".example.com"}, nil, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c = New(log.NewNopLogger(), fake.NewSimpleClientset(), zones, Config{Allowlist: allowlist, DNSType: "cname", TTL: 300, OwnerID: ownerID}, false, false, PolicySync, DeletionLimits{}, time.Minute)
+	c.recorder = record.NewFakeRecorder(100)
+	return c, zones, func() { os.RemoveAll(directory) }
+}
+
+// create the service as source of hosts whose status is written to the fake kubernetes api
+func newStatusSource(t *testing.T, c *Controller, name string) *corev1.Service {
+	service, err := c.kclient.CoreV1().Services("default").Create(&corev1.Service{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name, UID: types.UID(name)}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return service
+}
+
+// write the queued statuses of all resources like the status writer, but without waiting for the rate limit
+func writeStatuses(t *testing.T, c *Controller) {
+	c.statusMutex.Lock()
+	var keys []string
+	for key := range c.statuses {
+		keys = append(keys, key)
+	}
+	c.statusMutex.Unlock()
+	for _, key := range keys {
+		if err := c.writeStatus(key); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// return the status of the host written to the service, nil if there is none
+func hostStatusOf(t *testing.T, c *Controller, service *corev1.Service, host string) *hostStatus {
+	writeStatuses(t, c)
+	current, err := c.kclient.CoreV1().Services(service.Namespace).Get(service.Name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	statuses := map[string]*hostStatus{}
+	if value, ok := current.Annotations[statusAnnotation]; ok {
+		if err := json.Unmarshal([]byte(value), &statuses); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return statuses[host]
+}
+
+// write the record sets to the zone example.com
+func writeRecordSets(t *testing.T, zones provider.Provider, resourceRecordSets ...*route53.ResourceRecordSet) {
+	var changes []*route53.Change
+	for _, resourceRecordSet := range resourceRecordSets {
+		changes = append(changes, aws.NewRecordSetChange(route53.ChangeActionCreate, resourceRecordSet))
+	}
+	if _, err := zones.ApplyChanges("example.com", "", changes); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// return the names and types of the record sets of the zone example.com without its NS records
+func zoneRecordSets(t *testing.T, zones provider.Provider) map[string]bool {
+	resourceRecordSets, err := zones.ListRecordSets("example.com", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := make(map[string]bool)
+	for _, resourceRecordSet := range resourceRecordSets {
+		if *resourceRecordSet.Type != route53.RRTypeNs {
+			names[aws.NormalizeName(*resourceRecordSet.Name)+"/"+*resourceRecordSet.Type] = true
+		}
+	}
+	return names
+}
+
+// return the targets of the record sets of the zone example.com without its NS records by name and type
+func zoneTargets(t *testing.T, zones provider.Provider) map[string]string {
+	resourceRecordSets, err := zones.ListRecordSets("example.com", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	targets := make(map[string]string)
+	for _, resourceRecordSet := range resourceRecordSets {
+		if *resourceRecordSet.Type != route53.RRTypeNs {
+			targets[aws.NormalizeName(*resourceRecordSet.Name)+"/"+*resourceRecordSet.Type] = describeTarget(resourceRecordSet)
+		}
+	}
+	return targets
+}
+
+func TestPointsTo(t *testing.T) {
+	const lb = "app-123.eu-central-1.elb.amazonaws.com"
+	tests := []struct {
+		name    string
+		live    *route53.ResourceRecordSet
+		dnsName string
+		points  bool
+	}{
+		{"cname", aws.NewChange(route53.ChangeActionUpsert, lb, "", "a.example.com", "cname", 300).ResourceRecordSet, lb, true},
+		{"cname with dot and upper case", aws.NewResourceRecordSet("a.example.com", route53.RRTypeCname, 300, []string{"App-123.eu-central-1.elb.amazonaws.com."}), lb, true},
+		{"alias with dualstack prefix", aws.NewAliasResourceRecordSet("a.example.com", route53.RRTypeA, "dualstack."+lb+".", "Z215JYRZR1TBD5", false), lb, true},
+		{"other cname", aws.NewResourceRecordSet("a.example.com", route53.RRTypeCname, 300, []string{"other.example.com"}), lb, false},
+		{"other alias", aws.NewAliasResourceRecordSet("a.example.com", route53.RRTypeA, "other.example.com", "Z1", false), lb, false},
+		{"a record", aws.NewResourceRecordSet("a.example.com", route53.RRTypeA, 300, []string{"192.0.2.1"}), lb, false},
+		{"unknown target", aws.NewResourceRecordSet("a.example.com", route53.RRTypeCname, 300, []string{lb}), "", false},
+	}
+	for _, test := range tests {
+		if points := pointsTo(test.live, test.dnsName); points != test.points {
+			t.Errorf("%s: got %v, want %v", test.name, points, test.points)
+		}
+	}
+}
+
+func TestStaticLoadBalancers(t *testing.T) {
+	c := &Controller{logger: log.NewNopLogger()}
+	c.UseStaticLoadBalancers(map[string]string{"app": "app-123.eu-central-1.elb.amazonaws.com"})
+
+	tests := []struct {
+		target       loadBalancer
+		dnsName      string
+		hostedZoneID string
+	}{
+		{loadBalancer{name: "app"}, "app-123.eu-central-1.elb.amazonaws.com", ""},
+		{loadBalancer{name: "unknown"}, "", ""},
+		{loadBalancer{dnsName: "other-456.eu-central-1.elb.amazonaws.com"}, "other-456.eu-central-1.elb.amazonaws.com", ""},
+		{loadBalancer{}, "", ""},
+	}
+	for _, test := range tests {
+		dnsName, hostedZoneID := c.getLoadBalancerAttributes(test.target)
+		if dnsName != test.dnsName || hostedZoneID != test.hostedZoneID {
+			t.Errorf("%s: got %q, %q, want %q, %q", test.target, dnsName, hostedZoneID, test.dnsName, test.hostedZoneID)
+		}
+	}
+	if hostedZoneID := c.loadBalancerHostedZoneID("app-123.eu-central-1.elb.amazonaws.com"); hostedZoneID != "" {
+		t.Errorf("got hosted zone %q of a static load balancer, want none", hostedZoneID)
+	}
+}
+
+func TestApexHostWithoutAliasRecords(t *testing.T) {
+	c, zones, cleanup := newZoneFileController(t, "")
+	defer cleanup()
+	allowlist, err := NewAllowlist(nil, []string{"example.com", ".example.com"}, nil, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.config.Allowlist = allowlist
+	c.UseStaticLoadBalancers(map[string]string{"lb": "app-123.eu-central-1.elb.amazonaws.com"})
+	ingressObj := newIngress(t, c, "app", "lb", "example.com", "app.example.com")
+	c.Create(ingressObj)
+
+	// neither a CNAME nor an ALIAS record can be written at the apex, the other hosts are published
+	if sets := zoneRecordSets(t, zones); len(sets) != 1 || !sets["app.example.com/CNAME"] {
+		t.Errorf("got %v, want only the CNAME record set of app.example.com", sets)
+	}
+	var reported bool
+	for len(c.recorder.(*record.FakeRecorder).Events) > 0 {
+		if event := <-c.recorder.(*record.FakeRecorder).Events; strings.Contains(event, "ApexNotSupported") {
+			reported = true
+		}
+	}
+	if !reported {
+		t.Error("got no ApexNotSupported event for the apex host")
+	}
+}
+
+// recordingProvider records the actions and types of the change batches applied to the wrapped provider
+type recordingProvider struct {
+	provider.Provider
+	aliasRecords bool
+	batches      [][]string
+}
+
+func (r *recordingProvider) ApplyChanges(zoneID, roleARN string, changes []*route53.Change) (string, error) {
+	var batch []string
+	for _, change := range changes {
+		batch = append(batch, *change.Action+" "+*change.ResourceRecordSet.Type)
+	}
+	r.batches = append(r.batches, batch)
+	return r.Provider.ApplyChanges(zoneID, roleARN, changes)
+}
+
+func (r *recordingProvider) AliasRecords() bool {
+	return r.aliasRecords
+}
+
+func TestTypeMigration(t *testing.T) {
+	const (
+		a = "a-123.eu-central-1.elb.amazonaws.com"
+		b = "b-456.eu-central-1.elb.amazonaws.com"
+	)
+	tests := []struct {
+		name         string
+		dnsType      string
+		aliasRecords bool
+		existing     *route53.ResourceRecordSet
+		batches      [][]string
+		want         map[string]string
+	}{
+		{
+			name:     "same type is upserted",
+			dnsType:  "cname",
+			existing: aws.NewResourceRecordSet("app.example.com", route53.RRTypeCname, 300, []string{b}),
+			batches:  [][]string{{"UPSERT CNAME"}},
+			want:     map[string]string{"app.example.com/CNAME": a},
+		},
+		{
+			name:     "other type is deleted in the same batch",
+			dnsType:  "cname",
+			existing: aws.NewResourceRecordSet("app.example.com", route53.RRTypeA, 300, []string{"192.0.2.1"}),
+			batches:  [][]string{{"DELETE A", "UPSERT CNAME"}},
+			want:     map[string]string{"app.example.com/CNAME": a},
+		},
+		{
+			// the zone file rejects ALIAS records, so the whole batch fails and the host stays resolvable
+			name:         "failing migration keeps the old record set",
+			dnsType:      "alias",
+			aliasRecords: true,
+			existing:     aws.NewResourceRecordSet("app.example.com", route53.RRTypeCname, 300, []string{b}),
+			batches:      [][]string{{"DELETE CNAME", "UPSERT A"}},
+			want:         map[string]string{"app.example.com/CNAME": b},
+		},
+	}
+	for _, test := range tests {
+		c, zones, cleanup := newZoneFileController(t, "")
+		c.config.DNSType = test.dnsType
+		c.UseStaticLoadBalancers(map[string]string{"a": a})
+		writeRecordSets(t, zones, test.existing)
+		recorder := &recordingProvider{Provider: zones, aliasRecords: test.aliasRecords}
+		c.provider = recorder
+		c.Create(newIngress(t, c, "app", "a", "app.example.com"))
+
+		if !reflect.DeepEqual(recorder.batches, test.batches) {
+			t.Errorf("%s: got batches %v, want %v", test.name, recorder.batches, test.batches)
+		}
+		if got := zoneTargets(t, zones); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: got record sets %v, want %v", test.name, got, test.want)
+		}
+		cleanup()
+	}
+}
+
+func TestDNSType(t *testing.T) {
+	const lbZone = "Z215JYRZR1TBD5"
+	tests := []struct {
+		name         string
+		dnsType      string
+		aliasRecords bool
+		host         string
+		hostedZoneID string
+		want         string
+		ok           bool
+	}{
+		{"cname", "cname", true, "app.example.com", lbZone, "cname", true},
+		{"alias", "alias", true, "app.example.com", lbZone, "alias", true},
+		{"alias without ALIAS records", "alias", false, "app.example.com", lbZone, "cname", true},
+		{"apex of an AWS load balancer", "cname", true, "example.com", lbZone, "ALIAS", true},
+		{"apex of another target", "cname", true, "example.com", "", "cname", true},
+		{"apex with alias", "alias", true, "example.com", lbZone, "alias", true},
+		{"apex without ALIAS records", "cname", false, "example.com", lbZone, "", false},
+	}
+	for _, test := range tests {
+		c := &Controller{config: Config{DNSType: test.dnsType}, provider: &recordingProvider{aliasRecords: test.aliasRecords}}
+		got, ok := c.desiredDNSType(test.host, provider.Zone{Name: "example.com", ID: "example.com"}, test.hostedZoneID)
+		if got != test.want || ok != test.ok {
+			t.Errorf("%s: got %q, %v, want %q, %v", test.name, got, ok, test.want, test.ok)
+		}
+	}
+}
+
+func TestApexAlias(t *testing.T) {
+	c, zones, cleanup := newZoneFileController(t, "")
+	defer cleanup()
+	allowlist, err := NewAllowlist(nil, []string{"example.com"}, nil, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.config.Allowlist = allowlist
+	c.EnableLoadBalancerCache()
+	c.loadBalancers["lb"] = loadBalancerAttributes{dnsName: "app-123.eu-central-1.elb.amazonaws.com", hostedZoneID: "Z215JYRZR1TBD5"}
+	recorder := &recordingProvider{Provider: zones, aliasRecords: true}
+	c.provider = recorder
+	c.Create(newIngress(t, c, "app", "lb", "example.com"))
+
+	// the apex gets an ALIAS A record instead of the configured CNAME record
+	if want := [][]string{{"UPSERT A"}}; !reflect.DeepEqual(recorder.batches, want) {
+		t.Errorf("got batches %v, want %v", recorder.batches, want)
+	}
+	var reported bool
+	for len(c.recorder.(*record.FakeRecorder).Events) > 0 {
+		if event := <-c.recorder.(*record.FakeRecorder).Events; strings.Contains(event, "ApexAlias") {
+			reported = true
+		}
+	}
+	if !reported {
+		t.Error("got no ApexAlias event for the apex host")
+	}
+}
@@ -0,0 +1,68 @@
+package controller
+
+import (
+	"strings"
+
+	"github.com/aws/aws-sdk-go/service/route53"
+	"github.com/dbsystel/AmazonRoute53-ingress-controller/aws"
+)
+
+// hostDecision is the result of checking a host of a resource against the allowlist and the namespace policy
+type hostDecision struct {
+	// true if the host is allowed by the rule of the allowlist
+	allowed bool
+	rule    string
+	// true if the host is granted to the namespace of the resource by the grant, always true without namespace policy
+	delegated bool
+	grant     string
+	// error looking up the namespace of the resource
+	err error
+}
+
+// decide if a record set may be written for the host of the resource, without logging or reporting the decision.
+// The controller reports it by reportHostDecision, the planner by the reason.
+func (c *Controller) decideHost(host string, sourceObj recordSource) hostDecision {
+	decision := hostDecision{delegated: true}
+	decision.allowed, decision.rule = c.config.Allowlist.Evaluate(host)
+	if !decision.allowed || !c.namespacePolicy {
+		return decision
+	}
+
+	decision.delegated = false
+	namespace, err := c.namespaces.Get(sourceObj.GetNamespace())
+	if err != nil {
+		decision.err = err
+		return decision
+	}
+	for _, grant := range c.namespaceGrants(namespace.Annotations[domainsAnnotation]) {
+		if strings.HasPrefix(grant.pattern, ".") && strings.HasSuffix(host, grant.pattern) || grant.re.MatchString(host) {
+			decision.delegated = true
+			decision.grant = grant.pattern
+			break
+		}
+	}
+	return decision
+}
+
+// reason returns why a record set is written for the host of a resource in the namespace or not
+func (d hostDecision) reason(namespace string) string {
+	switch {
+	case !d.allowed:
+		return "not allowed by rule " + d.rule
+	case d.err != nil:
+		return "namespace " + namespace + " could not be read: " + d.err.Error()
+	case !d.delegated:
+		return "not granted to namespace " + namespace + " by its annotation " + domainsAnnotation
+	default:
+		return "allowed by rule " + d.rule
+	}
+}
+
+// return the changes creating or updating the record set of the host pointing to the target and its ownership record
+func (c *Controller) upsertChanges(host, aliasName, aliasHostedZoneID, dnsType string, sourceObj recordSource) []*route53.Change {
+	changes := []*route53.Change{aws.NewChange(route53.ChangeActionUpsert, aliasName, aliasHostedZoneID, host, dnsType, c.config.TTL)}
+	if ownership := c.ownershipUpsert(host, sourceObj); ownership != nil {
+		changes = append(changes, ownership)
+	}
+	return changes
+}
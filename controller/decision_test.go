@@ -0,0 +1,59 @@
+package controller
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+func TestDecideHost(t *testing.T) {
+	allowlist, err := NewAllowlist(nil, []string{".example.com"}, nil, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	indexer.Add(&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "team-a", Annotations: map[string]string{domainsAnnotation: ".a.example.com, app.example.com"}}})
+	c := &Controller{config: Config{Allowlist: allowlist}, namespacePolicy: true}
+	c.UseNamespaceLister(corelisters.NewNamespaceLister(indexer))
+
+	tests := []struct {
+		host      string
+		namespace string
+		allowed   bool
+		delegated bool
+		reason    string
+	}{
+		{"app.example.com", "team-a", true, true, "allowed by rule suffix:.example.com"},
+		{"web.a.example.com", "team-a", true, true, "allowed by rule suffix:.example.com"},
+		{"web.b.example.com", "team-a", true, false, "not granted to namespace team-a by its annotation " + domainsAnnotation},
+		{"app.example.org", "team-a", false, true, "not allowed by rule none"},
+		{"app.example.com", "team-b", true, false, `namespace team-b could not be read: namespace "team-b" not found`},
+	}
+	for _, test := range tests {
+		decision := c.decideHost(test.host, newPlannedIngressIn(test.namespace))
+		if decision.allowed != test.allowed || decision.delegated != test.delegated || decision.reason(test.namespace) != test.reason {
+			t.Errorf("%s in %s: got %v, %v, %q, want %v, %v, %q", test.host, test.namespace, decision.allowed, decision.delegated, decision.reason(test.namespace), test.allowed, test.delegated, test.reason)
+		}
+	}
+}
+
+func TestUpsertChanges(t *testing.T) {
+	c := &Controller{config: Config{TTL: 60}}
+	if changes := c.upsertChanges("app.example.com", "lb.example.net", "", "cname", newPlannedIngress("app")); len(changes) != 1 {
+		t.Errorf("got %d changes without owner ID, want the record set only", len(changes))
+	}
+	c.config.OwnerID = "ci"
+	changes := c.upsertChanges("*.example.com", "lb.example.net", "", "cname", newPlannedIngress("app"))
+	if len(changes) != 2 || *changes[1].ResourceRecordSet.Name != "_r53-ingress-owner._wildcard.example.com" {
+		t.Errorf("got %v, want the record set and its ownership record", changes)
+	}
+}
+
+func newPlannedIngressIn(namespace string) recordSource {
+	ingressObj := newPlannedIngress("app")
+	ingressObj.Namespace = namespace
+	return ingressObj
+}
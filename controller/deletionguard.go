@@ -0,0 +1,165 @@
+package controller
+
+import (
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/dbsystel/AmazonRoute53-ingress-controller/metrics"
+	"github.com/go-kit/kit/log/level"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// annotation which allows deleting the record sets of a resource even if the mass deletion limits are exceeded
+const allowMassDeletionAnnotation = "ingress.net/route53-allow-mass-deletion"
+
+// interval between retries of held back deletions
+const heldDeletionRetryInterval = 30 * time.Second
+
+// DeletionLimits defines how many record sets may be deleted within a time window before deletions are held back
+type DeletionLimits struct {
+	// maximum number of deletions within the window, 0 disables the limit
+	MaxDeletions int
+	// maximum percentage of the hosts managed at the start of the window deleted within the window, 0 disables the limit
+	MaxPercent float64
+	// minimum number of hosts managed at the start of the window for MaxPercent to apply
+	MinHosts int
+	Window   time.Duration
+	// if true, deletions are never held back
+	Override bool
+}
+
+// heldDeletion is a record set deletion held back by the deletion limits until it is allowed
+type heldDeletion struct {
+	// resource whose annotation may override the limits, updated by its events
+	sourceObj recordSource
+	// deletes the record set
+	delete func()
+}
+
+// delete the record set of the host (or of the DNSRecord key) of the resource by calling deleteFunc, unless the deletion limits
+// are exceeded. A held back deletion is reported as event on the resource and retried by retryHeldDeletions.
+func (c *Controller) guardDeletion(host string, sourceObj recordSource, deleteFunc func()) {
+	allowed, deletions, percent := c.reserveDeletion(host, sourceKey(sourceObj), overridesDeletionLimits(sourceObj))
+	if !allowed {
+		c.heldDeletions[host] = &heldDeletion{sourceObj: sourceObj, delete: deleteFunc}
+		metrics.HeldDeletions.Set(float64(len(c.heldDeletions)))
+		metrics.HeldDeletionsTotal.Inc()
+		level.Error(c.logger).Log("msg", "MASS DELETION GUARD: deletion of Route53 record set held back, too many deletions within the window. Set --allow-mass-deletion or the annotation "+allowMassDeletionAnnotation+" to proceed.", "hostName", host, "deletions", deletions, "percent", percent, "window", c.deletionLimits.Window, "heldDeletions", len(c.heldDeletions), "resource", sourceKey(sourceObj))
+		c.recorder.Eventf(sourceObj, corev1.EventTypeWarning, "DeletionHeld", "Deletion of Route53 record set for host %s held back: %d deletions (%.1f%% of managed hosts) within %s exceed the limits", host, deletions, percent, c.deletionLimits.Window)
+		return
+	}
+	c.releaseHeldDeletion(host)
+	deleteFunc()
+}
+
+// RunDeletionGuard retries the held back deletions periodically until stopCh is closed, so they are executed
+// as soon as earlier deletions left the window
+func (c *Controller) RunDeletionGuard(stopCh <-chan struct{}, wg *sync.WaitGroup) {
+	defer wg.Done()
+
+	ticker := time.NewTicker(heldDeletionRetryInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			c.mutex.Lock()
+			c.retryHeldDeletions()
+			c.mutex.Unlock()
+		case <-stopCh:
+			return
+		}
+	}
+}
+
+// update the resource of its held back deletions, whose override annotation may have changed, and retry them
+func (c *Controller) updateHeldDeletions(sourceObj recordSource) {
+	held := false
+	for _, deletion := range c.heldDeletions {
+		if sourceKey(deletion.sourceObj) == sourceKey(sourceObj) {
+			deletion.sourceObj = sourceObj
+			held = true
+		}
+	}
+	if held {
+		c.retryHeldDeletions()
+	}
+}
+
+// execute the held back deletions in the order of their hosts as far as the deletion limits allow
+func (c *Controller) retryHeldDeletions() {
+	hosts := make([]string, 0, len(c.heldDeletions))
+	for host := range c.heldDeletions {
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts)
+
+	for _, host := range hosts {
+		deletion := c.heldDeletions[host]
+		if allowed, _, _ := c.reserveDeletion(host, sourceKey(deletion.sourceObj), overridesDeletionLimits(deletion.sourceObj)); !allowed {
+			continue
+		}
+		c.releaseHeldDeletion(host)
+		metrics.HeldDeletionsCompletedTotal.Inc()
+		level.Info(c.logger).Log("msg", "Held back deletion of Route53 record set is executed now", "hostName", host, "heldDeletions", len(c.heldDeletions), "resource", sourceKey(deletion.sourceObj))
+		c.recorder.Eventf(deletion.sourceObj, corev1.EventTypeNormal, "DeletionResumed", "Held back deletion of Route53 record set for host %s is executed now", host)
+		deletion.delete()
+	}
+}
+
+// check the deletion limits for one more deletion of the host referenced by the resource and count it if it is allowed.
+// Returns the number and percentage of deletions within the window.
+func (c *Controller) reserveDeletion(host, resource string, override bool) (bool, int, float64) {
+	now := time.Now()
+	recent := c.deletions[:0]
+	for _, deletion := range c.deletions {
+		if now.Sub(deletion) < c.deletionLimits.Window {
+			recent = append(recent, deletion)
+		}
+	}
+	c.deletions = recent
+
+	if len(c.deletions) == 0 {
+		// the window starts with this deletion, the host is still counted as managed
+		c.managedAtWindowStart = len(c.hostReferences) + len(c.records) + 1
+	}
+	deletions := len(c.deletions) + 1
+	exceeded, percent := c.deletionLimits.exceeded(deletions, c.managedAtWindowStart)
+
+	if exceeded && !c.deletionLimits.Override && !override {
+		return false, deletions, percent
+	}
+	if exceeded {
+		level.Warn(c.logger).Log("msg", "Mass deletion limits exceeded, but deletion is allowed by override", "hostName", host, "deletions", deletions, "percent", percent, "resource", resource)
+	}
+	c.deletions = append(c.deletions, now)
+	return true, deletions, percent
+}
+
+// check if the number of deletions within the window exceeds the limits. The percentage is relative to the hosts
+// managed at the start of the window and only limited if there were at least MinHosts of them.
+func (l DeletionLimits) exceeded(deletions, managedAtWindowStart int) (bool, float64) {
+	percent := 0.0
+	if managedAtWindowStart > 0 {
+		percent = float64(deletions) * 100 / float64(managedAtWindowStart)
+	}
+	exceeded := l.MaxDeletions > 0 && deletions > l.MaxDeletions ||
+		l.MaxPercent > 0 && managedAtWindowStart >= l.MinHosts && percent > l.MaxPercent
+	return exceeded, percent
+}
+
+// check if the annotation of the resource allows deletions exceeding the deletion limits
+func overridesDeletionLimits(sourceObj recordSource) bool {
+	override, _ := strconv.ParseBool(sourceObj.GetAnnotations()[allowMassDeletionAnnotation])
+	return override
+}
+
+// forget a held back deletion, e.g. because the host is referenced again
+func (c *Controller) releaseHeldDeletion(host string) {
+	if _, ok := c.heldDeletions[host]; ok {
+		delete(c.heldDeletions, host)
+		metrics.HeldDeletions.Set(float64(len(c.heldDeletions)))
+	}
+}
@@ -0,0 +1,130 @@
+package controller
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/tools/record"
+)
+
+func TestDeletionLimitsExceeded(t *testing.T) {
+	tests := []struct {
+		name      string
+		limits    DeletionLimits
+		deletions int
+		managed   int
+		exceeded  bool
+		percent   float64
+	}{
+		{"no limits", DeletionLimits{}, 100, 100, false, 100},
+		{"below max deletions", DeletionLimits{MaxDeletions: 3}, 3, 100, false, 3},
+		{"above max deletions", DeletionLimits{MaxDeletions: 3}, 4, 100, true, 4},
+		{"below max percent", DeletionLimits{MaxPercent: 10, MinHosts: 10}, 10, 100, false, 10},
+		{"above max percent", DeletionLimits{MaxPercent: 10, MinHosts: 10}, 11, 100, true, 11},
+		{"last host below min hosts", DeletionLimits{MaxPercent: 10, MinHosts: 10}, 1, 1, false, 100},
+		{"all hosts below min hosts", DeletionLimits{MaxPercent: 10, MinHosts: 10}, 9, 9, false, 100},
+		{"all hosts at min hosts", DeletionLimits{MaxPercent: 10, MinHosts: 10}, 10, 10, true, 100},
+		{"max deletions below min hosts", DeletionLimits{MaxDeletions: 2, MaxPercent: 10, MinHosts: 10}, 3, 5, true, 60},
+		{"nothing managed", DeletionLimits{MaxPercent: 10}, 1, 0, false, 0},
+	}
+	for _, test := range tests {
+		exceeded, percent := test.limits.exceeded(test.deletions, test.managed)
+		if exceeded != test.exceeded || percent != test.percent {
+			t.Errorf("%s: got %v, %.1f, want %v, %.1f", test.name, exceeded, percent, test.exceeded, test.percent)
+		}
+	}
+}
+
+// create a controller without provider which only counts deletions
+func newDeletionGuardController(limits DeletionLimits, managedHosts int) *Controller {
+	c := &Controller{
+		logger:         log.NewNopLogger(),
+		recorder:       record.NewFakeRecorder(100),
+		deletionLimits: limits,
+		heldDeletions:  make(map[string]*heldDeletion),
+		hostReferences: make(map[string]map[string]*hostReference),
+		records:        make(map[string]string),
+	}
+	for i := 0; i < managedHosts; i++ {
+		c.hostReferences[string(rune('a'+i))+".example.com"] = map[string]*hostReference{}
+	}
+	return c
+}
+
+func newDeletionSource(name string, override bool) *unstructured.Unstructured {
+	source := &unstructured.Unstructured{}
+	source.SetAPIVersion("v1")
+	source.SetKind("Service")
+	source.SetNamespace("default")
+	source.SetName(name)
+	if override {
+		source.SetAnnotations(map[string]string{allowMassDeletionAnnotation: "true"})
+	}
+	return source
+}
+
+func TestGuardDeletionPercentAgainstWindowStart(t *testing.T) {
+	c := newDeletionGuardController(DeletionLimits{MaxPercent: 20, MinHosts: 10, Window: time.Hour}, 10)
+	source := newDeletionSource("app", false)
+
+	deleted := 0
+	// the first deletion fixes 10 managed hosts, each deletion is 10 percent of them although fewer hosts remain
+	for _, host := range []string{"x.example.com", "y.example.com", "z.example.com"} {
+		delete(c.hostReferences, string(rune('a'+deleted))+".example.com")
+		c.guardDeletion(host, source, func() { deleted++ })
+	}
+	if deleted != 2 {
+		t.Errorf("got %d deletions, want 2", deleted)
+	}
+	if _, ok := c.heldDeletions["z.example.com"]; !ok || len(c.heldDeletions) != 1 {
+		t.Errorf("got held deletions %v, want z.example.com", c.heldDeletions)
+	}
+}
+
+func TestRetryHeldDeletions(t *testing.T) {
+	c := newDeletionGuardController(DeletionLimits{MaxDeletions: 1, Window: time.Hour}, 0)
+	source := newDeletionSource("app", false)
+
+	deleted := []string{}
+	for _, host := range []string{"a.example.com", "b.example.com", "c.example.com"} {
+		host := host
+		c.guardDeletion(host, source, func() { deleted = append(deleted, host) })
+	}
+	if len(deleted) != 1 || len(c.heldDeletions) != 2 {
+		t.Fatalf("got deletions %v and %d held, want 1 and 2 held", deleted, len(c.heldDeletions))
+	}
+
+	c.retryHeldDeletions()
+	if len(deleted) != 1 {
+		t.Errorf("got deletions %v within the window, want 1", deleted)
+	}
+
+	// the earlier deletion left the window
+	c.deletions[0] = time.Now().Add(-2 * time.Hour)
+	c.retryHeldDeletions()
+	if len(deleted) != 2 || deleted[1] != "b.example.com" {
+		t.Errorf("got deletions %v after the window moved, want b.example.com next", deleted)
+	}
+
+	// the override annotation releases the rest
+	c.updateHeldDeletions(newDeletionSource("app", true))
+	if len(deleted) != 3 || len(c.heldDeletions) != 0 {
+		t.Errorf("got deletions %v and %d held after override, want all", deleted, len(c.heldDeletions))
+	}
+}
+
+func TestReleaseHeldDeletion(t *testing.T) {
+	c := newDeletionGuardController(DeletionLimits{MaxDeletions: 0, MaxPercent: 1, MinHosts: 1, Window: time.Hour}, 1)
+	called := false
+	c.guardDeletion("a.example.com", newDeletionSource("app", false), func() { called = true })
+	if called {
+		t.Fatal("deletion of the only host should be held back")
+	}
+	c.releaseHeldDeletion("a.example.com")
+	c.retryHeldDeletions()
+	if called || len(c.heldDeletions) != 0 {
+		t.Error("a released deletion must not be executed")
+	}
+}
@@ -0,0 +1,484 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	awssdk "github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/route53"
+	"github.com/dbsystel/AmazonRoute53-ingress-controller/aws"
+	"github.com/go-kit/kit/log/level"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// API group of the DNSRecord custom resource
+const dnsRecordGroup = "route53.ingress.net"
+
+// DNSRecordResource is the custom resource of declarative DNS records
+var DNSRecordResource = schema.GroupVersionResource{Group: dnsRecordGroup, Version: "v1alpha1", Resource: "dnsrecords"}
+
+// record types which may be declared by a DNSRecord
+var dnsRecordTypes = []string{
+	route53.RRTypeA, route53.RRTypeAaaa, route53.RRTypeCaa, route53.RRTypeCname, route53.RRTypeMx,
+	route53.RRTypeNaptr, route53.RRTypePtr, route53.RRTypeSpf, route53.RRTypeSrv, route53.RRTypeTxt,
+}
+
+// dnsRecordSpec is the spec of a DNSRecord
+type dnsRecordSpec struct {
+	Name          string            `json:"name"`
+	Type          string            `json:"type"`
+	TTL           *int64            `json:"ttl,omitempty"`
+	Values        []string          `json:"values,omitempty"`
+	Alias         *dnsRecordAlias   `json:"alias,omitempty"`
+	RoutingPolicy *dnsRoutingPolicy `json:"routingPolicy,omitempty"`
+}
+
+// dnsRecordAlias is the target of an alias record
+type dnsRecordAlias struct {
+	DNSName string `json:"dnsName"`
+	// looked up for AWS load balancers if empty
+	HostedZoneID         string `json:"hostedZoneID,omitempty"`
+	EvaluateTargetHealth bool   `json:"evaluateTargetHealth,omitempty"`
+}
+
+// dnsRoutingPolicy is the routing policy of a record set, exactly one of weight, region and failover is set
+type dnsRoutingPolicy struct {
+	SetIdentifier string  `json:"setIdentifier"`
+	Weight        *int64  `json:"weight,omitempty"`
+	Region        string  `json:"region,omitempty"`
+	Failover      string  `json:"failover,omitempty"`
+	HealthCheckID *string `json:"healthCheckID,omitempty"`
+}
+
+// DNSRecordController handles the events of DNSRecord custom resources
+type DNSRecordController struct {
+	*Controller
+}
+
+// DNSRecords returns the handler for events of DNSRecords, which shares the ownership records and limits with the other sources
+func (c *Controller) DNSRecords() *DNSRecordController {
+	return &DNSRecordController{Controller: c}
+}
+
+// Create will do something when a DNSRecord is beeing created
+func (d *DNSRecordController) Create(obj interface{}) {
+	level.Debug(d.logger).Log("msg", "Called function: Create")
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	defer d.track("create")()
+	recordObj := obj.(*unstructured.Unstructured)
+
+	level.Info(d.logger).Log("msg", "Creation of a DNS record detected", "resource", sourceKey(recordObj))
+	d.upsertDNSRecord(recordObj)
+}
+
+// Update will do something when a DNSRecord is beeing updated
+func (d *DNSRecordController) Update(oldobj interface{}, newobj interface{}) {
+	newRecordObj := newobj.(*unstructured.Unstructured)
+	oldRecordObj := oldobj.(*unstructured.Unstructured)
+
+	level.Debug(d.logger).Log("msg", "Called function: Update")
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	defer d.track("update")()
+	d.updateHeldDeletions(newRecordObj)
+
+	// the generation only changes with the spec, not with the status written by the controller
+	if newRecordObj.GetGeneration() == oldRecordObj.GetGeneration() {
+		level.Debug(d.logger).Log("msg", "Skipping automatically updated DNS record", "resource", sourceKey(newRecordObj))
+		return
+	}
+
+	level.Info(d.logger).Log("msg", "Update of a DNS record detected", "resource", sourceKey(newRecordObj))
+	oldSpec, oldErr := parseDNSRecord(oldRecordObj)
+	newSpec, newErr := parseDNSRecord(newRecordObj)
+	if oldErr == nil && (newErr != nil || dnsRecordKey(oldSpec) != dnsRecordKey(newSpec)) {
+		// the record set is replaced by another one
+		d.deleteDNSRecord(oldRecordObj)
+	}
+	d.upsertDNSRecord(newRecordObj)
+}
+
+// Delete will do something when a DNSRecord is beeing deleted
+func (d *DNSRecordController) Delete(obj interface{}) {
+	level.Debug(d.logger).Log("msg", "Called function: Delete")
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	defer d.track("delete")()
+	recordObj := obj.(*unstructured.Unstructured)
+
+	level.Info(d.logger).Log("msg", "Deletion of a DNS record detected", "resource", sourceKey(recordObj))
+	d.deleteDNSRecord(recordObj)
+	d.forgetStatus(recordObj)
+}
+
+// create or update the record set declared by the DNSRecord and report the result in its status
+func (d *DNSRecordController) upsertDNSRecord(recordObj *unstructured.Unstructured) {
+	spec, err := parseDNSRecord(recordObj)
+	if err == nil && d.config.OwnerID != "" {
+		err = validateOwnershipName(spec.Name)
+	}
+	if err != nil {
+		level.Warn(d.logger).Log("msg", "Invalid DNS record. Skipping!", "err", err.Error(), "resource", sourceKey(recordObj))
+		d.recorder.Eventf(recordObj, corev1.EventTypeWarning, "InvalidRecord", "DNS record is invalid: %v", err)
+		d.setRecordCondition(recordObj, false, "Invalid", err.Error())
+		return
+	}
+	name := spec.Name
+	if decision := d.decideHost(name, recordObj); !d.reportHostDecision(name, decision, recordObj) {
+		if !decision.allowed {
+			d.setRecordCondition(recordObj, false, "NotAllowed", "name "+name+" is not in the allowlist")
+		} else {
+			d.setRecordCondition(recordObj, false, "NotDelegated", "name "+name+" is "+decision.reason(recordObj.GetNamespace()))
+		}
+		return
+	}
+	key := dnsRecordKey(spec)
+	if claimant, ok := d.records[key]; ok && claimant != sourceKey(recordObj) {
+		message := fmt.Sprintf("record set %s is already declared by %s", key, claimant)
+		d.recorder.Event(recordObj, corev1.EventTypeWarning, "RecordConflict", message)
+		d.setRecordCondition(recordObj, false, "Conflict", message)
+		return
+	}
+	if hostRecordType(spec.Type) && len(d.hostReferences[name]) > 0 {
+		message := fmt.Sprintf("name %s is the host of %s, whose record set is managed by the controller", name, sourceKey(d.owner(name).source))
+		d.recorder.Event(recordObj, corev1.EventTypeWarning, "RecordConflict", message)
+		d.setRecordCondition(recordObj, false, "Conflict", message)
+		return
+	}
+	d.records[key] = sourceKey(recordObj)
+	d.releaseHeldDeletion(key)
+
+	hostedZone := d.searchHostedZone(name)
+	if hostedZone.ID == "" {
+		d.setRecordCondition(recordObj, false, "NoHostedZone", "no hosted zone found for name "+name)
+		return
+	}
+	roleARN := d.zoneRole(name)
+	live, err := d.liveDNSRecord(spec, hostedZone.ID, roleARN)
+	if err != nil {
+		d.handleError(err)
+		d.setRecordCondition(recordObj, false, "ChangeFailed", err.Error())
+		return
+	}
+	adoption, owner, err := d.decideAdoption(name, live, hostedZone.ID, roleARN)
+	if err != nil {
+		d.handleError(err)
+		d.setRecordCondition(recordObj, false, "ChangeFailed", err.Error())
+		return
+	}
+	if adoption == adoptionConflict {
+		d.reportOwnershipConflict(name, owner, recordObj)
+		d.setRecordCondition(recordObj, false, "OwnershipConflict", "existing record set is owned by "+owner+" and is kept")
+		return
+	}
+	if adoption == adoptionAdopt {
+		difference, err := d.adopt(name, live, d.dnsRecordSet(spec), hostedZone.ID, roleARN, recordObj)
+		switch {
+		case err != nil:
+			d.setRecordCondition(recordObj, false, "ChangeFailed", err.Error())
+		case difference != "":
+			d.setRecordCondition(recordObj, false, "AdoptionSkipped", "existing record set differs and is kept: "+difference)
+		default:
+			d.setRecordCondition(recordObj, true, "Adopted", "existing record set is adopted unchanged")
+		}
+		return
+	}
+	if allowed, reason := d.allowedByPolicy(route53.ChangeActionUpsert, name, live, hostedZone.ID, roleARN, recordObj); !allowed {
+		d.setRecordCondition(recordObj, false, "PolicySkipped", reason)
+		return
+	}
+
+	changes := []*route53.Change{aws.NewRecordSetChange(route53.ChangeActionUpsert, d.dnsRecordSet(spec))}
+	if ownership := d.ownershipUpsert(name, recordObj); ownership != nil {
+		changes = append(changes, ownership)
+	}
+	changeID, err := d.submitChanges(hostedZone.ID, roleARN, changes)
+	if err != nil {
+		d.setRecordCondition(recordObj, false, "ChangeFailed", err.Error())
+		return
+	}
+	level.Info(d.logger).Log("msg", "Submitted Route53 change", "action", route53.ChangeActionUpsert, "changeID", changeID, "hostName", name, "type", spec.Type, "resource", sourceKey(recordObj))
+	d.trackChange(changeID, roleARN, route53.ChangeActionUpsert, name, recordObj)
+}
+
+// delete the record set declared by the DNSRecord if it was created for it
+func (d *DNSRecordController) deleteDNSRecord(recordObj *unstructured.Unstructured) {
+	spec, err := parseDNSRecord(recordObj)
+	if err != nil {
+		return
+	}
+	name := spec.Name
+	key := dnsRecordKey(spec)
+	if d.records[key] != sourceKey(recordObj) {
+		level.Info(d.logger).Log("msg", "The record set "+key+" is not declared by this DNS record. Deletion Skipped.", "resource", sourceKey(recordObj))
+		return
+	}
+	delete(d.records, key)
+
+	if !d.isInAllowlist(name, recordObj) {
+		return
+	}
+	d.guardDeletion(key, recordObj, func() {
+		d.deleteDNSRecordSet(spec, recordObj)
+	})
+}
+
+// delete the record set declared by the DNSRecord and its ownership record if the name is no longer in use
+func (d *DNSRecordController) deleteDNSRecordSet(spec *dnsRecordSpec, recordObj *unstructured.Unstructured) {
+	name := spec.Name
+	hostedZone := d.searchHostedZone(name)
+	roleARN := d.zoneRole(name)
+	live, err := d.liveDNSRecord(spec, hostedZone.ID, roleARN)
+	if err != nil {
+		d.handleError(err)
+		return
+	}
+	adoption, owner, err := d.decideAdoption(name, live, hostedZone.ID, roleARN)
+	if err != nil {
+		d.handleError(err)
+		return
+	}
+	switch adoption {
+	case adoptionConflict:
+		level.Info(d.logger).Log("msg", "Existing Route53 record set is owned by another owner, deletion skipped", "owner", owner, "hostName", name, "resource", sourceKey(recordObj))
+		return
+	case adoptionAdopt:
+		level.Info(d.logger).Log("msg", "Existing Route53 record set was not adopted, deletion skipped", "hostName", name, "resource", sourceKey(recordObj))
+		return
+	}
+	if allowed, reason := d.allowedByPolicy(route53.ChangeActionDelete, name, live, hostedZone.ID, roleARN, recordObj); !allowed {
+		level.Info(d.logger).Log("msg", "Route53 change skipped by policy", "policy", d.policy, "reason", reason, "action", route53.ChangeActionDelete, "hostName", name, "resource", sourceKey(recordObj))
+		return
+	}
+
+	var changes []*route53.Change
+	if live != nil {
+		// a DELETE has to match the live record set exactly
+		changes = append(changes, aws.NewRecordSetChange(route53.ChangeActionDelete, live))
+	}
+	// the ownership record is kept as long as other record sets of the name are managed
+	if !d.nameInUse(name) {
+		if ownership := d.ownershipDeletion(name, hostedZone.ID, roleARN); ownership != nil {
+			changes = append(changes, ownership)
+		}
+	}
+	if len(changes) == 0 {
+		return
+	}
+	changeID, err := d.submitChanges(hostedZone.ID, roleARN, changes)
+	if err != nil {
+		return
+	}
+	level.Info(d.logger).Log("msg", "Submitted Route53 change", "action", route53.ChangeActionDelete, "changeID", changeID, "hostName", name, "type", spec.Type, "resource", sourceKey(recordObj))
+	d.trackChange(changeID, roleARN, route53.ChangeActionDelete, name, recordObj)
+}
+
+// check if another DNSRecord or a resource with load balancer still manages a record set of the name
+func (c *Controller) nameInUse(name string) bool {
+	if len(c.hostReferences[name]) > 0 {
+		return true
+	}
+	for key := range c.records {
+		if strings.HasPrefix(key, name+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// return the live record set declared by the spec, or nil if it does not exist
+func (c *Controller) liveDNSRecord(spec *dnsRecordSpec, hostedZoneID, roleARN string) (*route53.ResourceRecordSet, error) {
+	resourceRecordSets, err := c.provider.RecordSets(hostedZoneID, roleARN, spec.Name)
+	if err != nil {
+		return nil, err
+	}
+	for _, resourceRecordSet := range resourceRecordSets {
+		if *resourceRecordSet.Type == spec.Type && awssdk.StringValue(resourceRecordSet.SetIdentifier) == setIdentifier(spec) {
+			return resourceRecordSet, nil
+		}
+	}
+	return nil, nil
+}
+
+// check if a record set of the type conflicts with the A or CNAME record set of a host of a resource
+func hostRecordType(recordType string) bool {
+	return recordType == route53.RRTypeA || recordType == route53.RRTypeCname
+}
+
+// return the DNSRecord declaring an A or CNAME record set of the name, which conflicts with the record set of a host,
+// or an empty string
+func (c *Controller) hostRecordClaimant(name string) string {
+	for key, resource := range c.records {
+		parts := strings.SplitN(key, "/", 3)
+		if parts[0] == name && len(parts) > 1 && hostRecordType(parts[1]) {
+			return resource
+		}
+	}
+	return ""
+}
+
+// return the record set declared by the spec
+func (c *Controller) dnsRecordSet(spec *dnsRecordSpec) *route53.ResourceRecordSet {
+	var resourceRecordSet *route53.ResourceRecordSet
+	if spec.Alias != nil {
+		hostedZoneID := spec.Alias.HostedZoneID
+		if hostedZoneID == "" {
+			hostedZoneID = c.loadBalancerHostedZoneID(spec.Alias.DNSName)
+		}
+		resourceRecordSet = aws.NewAliasResourceRecordSet(spec.Name, spec.Type, spec.Alias.DNSName, hostedZoneID, spec.Alias.EvaluateTargetHealth)
+	} else {
+		ttl := c.config.TTL
+		if spec.TTL != nil {
+			ttl = *spec.TTL
+		}
+		resourceRecordSet = aws.NewResourceRecordSet(spec.Name, spec.Type, ttl, spec.Values)
+	}
+	if policy := spec.RoutingPolicy; policy != nil {
+		resourceRecordSet.SetIdentifier = awssdk.String(policy.SetIdentifier)
+		resourceRecordSet.Weight = policy.Weight
+		resourceRecordSet.HealthCheckId = policy.HealthCheckID
+		if policy.Region != "" {
+			resourceRecordSet.Region = awssdk.String(policy.Region)
+		}
+		if policy.Failover != "" {
+			resourceRecordSet.Failover = awssdk.String(policy.Failover)
+		}
+	}
+	return resourceRecordSet
+}
+
+// parse and validate the spec of the DNSRecord, the name is normalized and TXT values are quoted
+func parseDNSRecord(recordObj *unstructured.Unstructured) (*dnsRecordSpec, error) {
+	fields, _, _ := unstructured.NestedMap(recordObj.Object, "spec")
+	spec := &dnsRecordSpec{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(fields, spec); err != nil {
+		return nil, err
+	}
+
+	name, err := normalizeName(spec.Name, true)
+	if err != nil {
+		return nil, err
+	}
+	spec.Name = name
+	spec.Type = strings.ToUpper(spec.Type)
+	if !contains(dnsRecordTypes, spec.Type) {
+		return nil, fmt.Errorf("invalid type %q, must be one of: %v", spec.Type, dnsRecordTypes)
+	}
+	if spec.TTL != nil && (*spec.TTL < 0 || *spec.TTL > 2147483647) {
+		return nil, fmt.Errorf("invalid ttl %d, must be between 0 and 2147483647", *spec.TTL)
+	}
+
+	switch {
+	case spec.Alias != nil && len(spec.Values) > 0:
+		return nil, errors.New("values and alias are mutually exclusive")
+	case spec.Alias != nil:
+		if spec.Type != route53.RRTypeA && spec.Type != route53.RRTypeAaaa {
+			return nil, errors.New("alias records must have type A or AAAA")
+		}
+		if spec.Alias.DNSName == "" {
+			return nil, errors.New("alias must have a dnsName")
+		}
+	case len(spec.Values) == 0:
+		return nil, errors.New("either values or alias must be set")
+	}
+
+	if spec.Type == route53.RRTypeTxt || spec.Type == route53.RRTypeSpf {
+		for i, value := range spec.Values {
+			if !strings.HasPrefix(value, `"`) {
+				spec.Values[i] = strconv.Quote(value)
+			}
+		}
+	}
+
+	if policy := spec.RoutingPolicy; policy != nil {
+		if policy.SetIdentifier == "" {
+			return nil, errors.New("routingPolicy must have a setIdentifier")
+		}
+		policies := 0
+		if policy.Weight != nil {
+			policies++
+		}
+		if policy.Region != "" {
+			policies++
+		}
+		if policy.Failover != "" {
+			policies++
+			if policy.Failover != route53.ResourceRecordSetFailoverPrimary && policy.Failover != route53.ResourceRecordSetFailoverSecondary {
+				return nil, fmt.Errorf("invalid failover %q, must be one of: [PRIMARY, SECONDARY]", policy.Failover)
+			}
+		}
+		if policies != 1 {
+			return nil, errors.New("routingPolicy must have exactly one of weight, region and failover")
+		}
+	}
+	return spec, nil
+}
+
+// return the identifier of the record set declared by the spec, e.g. example.com/TXT or example.com/A/blue
+func dnsRecordKey(spec *dnsRecordSpec) string {
+	key := spec.Name + "/" + spec.Type
+	if identifier := setIdentifier(spec); identifier != "" {
+		key += "/" + identifier
+	}
+	return key
+}
+
+// return the set identifier of the routing policy of the spec, or an empty string
+func setIdentifier(spec *dnsRecordSpec) string {
+	if spec.RoutingPolicy == nil {
+		return ""
+	}
+	return spec.RoutingPolicy.SetIdentifier
+}
+
+// check if the list contains the value
+func contains(list []string, value string) bool {
+	for _, entry := range list {
+		if entry == value {
+			return true
+		}
+	}
+	return false
+}
+
+// return the status of the DNSRecord with the status of its change and its Ready condition
+func recordStatus(recordObj *unstructured.Unstructured, change *hostStatus, condition *recordCondition) map[string]interface{} {
+	status := map[string]interface{}{"observedGeneration": recordObj.GetGeneration()}
+	if change.ChangeID != "" {
+		status["changeID"] = change.ChangeID
+		status["change"] = change.Change
+		status["propagationSeconds"] = change.PropagationSeconds
+	}
+	if condition == nil {
+		return status
+	}
+	conditionStatus := string(metav1.ConditionFalse)
+	if condition.ready {
+		conditionStatus = string(metav1.ConditionTrue)
+	}
+	transition := time.Now().UTC().Format(time.RFC3339)
+	conditions, _, _ := unstructured.NestedSlice(recordObj.Object, "status", "conditions")
+	for _, previous := range conditions {
+		if fields, ok := previous.(map[string]interface{}); ok && fields["type"] == "Ready" && fields["status"] == conditionStatus {
+			if previous, ok := fields["lastTransitionTime"].(string); ok {
+				transition = previous
+			}
+		}
+	}
+	status["conditions"] = []map[string]interface{}{{
+		"type":               "Ready",
+		"status":             conditionStatus,
+		"reason":             condition.reason,
+		"message":            condition.message,
+		"lastTransitionTime": transition,
+	}}
+	return status
+}
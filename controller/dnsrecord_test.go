@@ -0,0 +1,27 @@
+package controller
+
+import "testing"
+
+func TestHostRecordClaimant(t *testing.T) {
+	c := &Controller{records: map[string]string{
+		"a.example.com/CNAME":          "dnsrecord/default/a",
+		"b.example.com/TXT":            "dnsrecord/default/b",
+		"c.example.com/A/eu-central-1": "dnsrecord/default/c",
+		"d.example.com/AAAA":           "dnsrecord/default/d",
+	}}
+	tests := []struct {
+		name     string
+		claimant string
+	}{
+		{"a.example.com", "dnsrecord/default/a"},
+		{"b.example.com", ""},
+		{"c.example.com", "dnsrecord/default/c"},
+		{"d.example.com", ""},
+		{"example.com", ""},
+	}
+	for _, test := range tests {
+		if claimant := c.hostRecordClaimant(test.name); claimant != test.claimant {
+			t.Errorf("hostRecordClaimant(%q) = %q, want %q", test.name, claimant, test.claimant)
+		}
+	}
+}
@@ -0,0 +1,172 @@
+package controller
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/route53"
+	"github.com/dbsystel/AmazonRoute53-ingress-controller/aws"
+	"github.com/dbsystel/AmazonRoute53-ingress-controller/metrics"
+	"github.com/go-kit/kit/log/level"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+// DriftedRecord is the record set of a managed host whose live values differ from the desired ones, e.g. after an edit in the console
+type DriftedRecord struct {
+	Host string `json:"host"`
+	Zone string `json:"zone"`
+	// owner of the host, whose load balancer is the desired target
+	Resource   string `json:"resource"`
+	Difference string `json:"difference"`
+	// one of: detected, fixed, dry_run, not_fixed
+	Result string `json:"result"`
+}
+
+// EnableDriftFix makes the drift detection correct drifted record sets instead of only reporting them
+func (c *Controller) EnableDriftFix() {
+	c.fixDrift = true
+}
+
+// RunDriftDetection compares the live record sets of all managed hosts with the desired ones periodically until stopCh is closed.
+// The first pass starts one interval after all informers have synced, so the handlers have seen all resources.
+func (c *Controller) RunDriftDetection(interval time.Duration, stopCh <-chan struct{}, wg *sync.WaitGroup, synced ...cache.InformerSynced) {
+	defer wg.Done()
+
+	if !cache.WaitForCacheSync(stopCh, synced...) {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			c.DetectDrift()
+		case <-stopCh:
+			return
+		}
+	}
+}
+
+// DetectDrift compares the live A or CNAME record set of every host referenced by a resource with the record set
+// its owner would write. Differences of type, target and TTL and missing record sets are reported as drift and,
+// if enabled, corrected with the same rules as any other change. Record sets of DNSRecords are not checked.
+// The live record sets are read without blocking event handlers, only the comparison and the correction of each host
+// happen while events are not handled.
+func (c *Controller) DetectDrift() []DriftedRecord {
+	defer observe("drift")()
+	c.configMutex.RLock()
+	defer c.configMutex.RUnlock()
+
+	c.mutex.Lock()
+	owners := c.hostOwners()
+	c.mutex.Unlock()
+
+	drifted := []DriftedRecord{}
+	level.Info(c.logger).Log("msg", "Drift detection started", "hosts", len(owners), "fixDrift", c.fixDrift)
+	for _, owner := range owners {
+		if allowed, _ := c.config.Allowlist.Evaluate(owner.host); !allowed {
+			continue
+		}
+		if record, ok := c.detectHostDrift(owner); ok {
+			metrics.DriftedRecordsTotal.WithLabelValues(record.Result).Inc()
+			drifted = append(drifted, record)
+		}
+	}
+	metrics.DriftedRecords.Set(float64(len(drifted)))
+	level.Info(c.logger).Log("msg", "Drift detection finished", "drifted", len(drifted))
+	return drifted
+}
+
+// hostOwner is a copy of the owner of a referenced host, which is read while events are handled
+type hostOwner struct {
+	host   string
+	source recordSource
+	target loadBalancer
+}
+
+// return copies of the owners of all referenced hosts sorted by host
+func (c *Controller) hostOwners() []hostOwner {
+	var owners []hostOwner
+	for _, host := range c.sortedHosts() {
+		if owner := c.owner(host); owner != nil {
+			owners = append(owners, hostOwner{host: host, source: owner.source, target: owner.target})
+		}
+	}
+	return owners
+}
+
+// compare the live record set of the host with the desired one of its owner and correct it if enabled, returns false
+// if it has not drifted or if the owner or its target changed since the owners were copied
+func (c *Controller) detectHostDrift(owner hostOwner) (DriftedRecord, bool) {
+	host := owner.host
+	hostedZone := c.searchHostedZone(host)
+	if hostedZone.ID == "" {
+		return DriftedRecord{}, false
+	}
+	roleARN := c.zoneRole(host)
+	live, err := c.liveRecordSet(host, hostedZone.ID, roleARN)
+	if err != nil {
+		c.handleError(err)
+		return DriftedRecord{}, false
+	}
+	if live != nil && c.config.OwnerID != "" && !c.isOwned(host, hostedZone.ID, roleARN) {
+		// the record set was never taken over, e.g. by the policy create-only or in adoption mode
+		return DriftedRecord{}, false
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if current := c.owner(host); current == nil || current.source.GetUID() != owner.source.GetUID() || current.target != owner.target {
+		level.Debug(c.logger).Log("msg", "Owner of the host changed during the drift detection, skipped", "hostName", host)
+		return DriftedRecord{}, false
+	}
+	aliasName, aliasHostedZoneID := c.getLoadBalancerAttributes(owner.target)
+	if aliasName == "" {
+		level.Debug(c.logger).Log("msg", "Load balancer not found, drift detection skipped", "hostName", host, "target", owner.target.String())
+		return DriftedRecord{}, false
+	}
+
+	dnsType, ok := c.desiredDNSType(host, hostedZone, aliasHostedZoneID)
+	if !ok {
+		return DriftedRecord{}, false
+	}
+	desired := aws.NewChange(route53.ChangeActionUpsert, aliasName, aliasHostedZoneID, host, dnsType, c.config.TTL).ResourceRecordSet
+	difference := driftDifference(live, desired)
+	if difference == "" {
+		return DriftedRecord{}, false
+	}
+
+	record := DriftedRecord{Host: host, Zone: hostedZone.Name, Resource: sourceKey(owner.source), Difference: difference, Result: "detected"}
+	level.Warn(c.logger).Log("msg", "Route53 record set has drifted from the desired one", "difference", difference, "hostName", host, "resource", record.Resource)
+	c.recorder.Eventf(owner.source, corev1.EventTypeWarning, "RecordDrifted", "Route53 record set of host %s differs from the desired one: %s", host, difference)
+	if !c.fixDrift {
+		return record, true
+	}
+
+	record.Result = "not_fixed"
+	if c.changeRecordSet(route53.ChangeActionUpsert, aliasName, aliasHostedZoneID, host, hostedZone.ID, dnsType, owner.source) {
+		record.Result = "fixed"
+		if c.dryRun {
+			record.Result = "dry_run"
+		}
+		c.recorder.Eventf(owner.source, corev1.EventTypeNormal, "RecordDriftFixed", "Route53 record set of host %s is reset to the desired one", host)
+	}
+	return record, true
+}
+
+// return a description of the differences between the live and the desired record set including the TTL, or an empty string
+func driftDifference(live, desired *route53.ResourceRecordSet) string {
+	if live == nil {
+		return "record set is missing"
+	}
+	if difference := recordSetDifference(live, desired); difference != "" {
+		return difference
+	}
+	if live.AliasTarget == nil && live.TTL != nil && *live.TTL != *desired.TTL {
+		return fmt.Sprintf("ttl %d instead of %d", *live.TTL, *desired.TTL)
+	}
+	return ""
+}
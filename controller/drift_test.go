@@ -0,0 +1,103 @@
+package controller
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/service/route53"
+	"github.com/dbsystel/AmazonRoute53-ingress-controller/aws"
+)
+
+func TestDriftDifference(t *testing.T) {
+	const lb = "app-123.eu-central-1.elb.amazonaws.com"
+	cname := aws.NewResourceRecordSet("app.example.com", route53.RRTypeCname, 300, []string{lb})
+	alias := aws.NewAliasResourceRecordSet("app.example.com", route53.RRTypeA, lb, "Z215JYRZR1TBD5", false)
+	tests := []struct {
+		name       string
+		live       *route53.ResourceRecordSet
+		desired    *route53.ResourceRecordSet
+		difference string
+	}{
+		{"missing", nil, cname, "record set is missing"},
+		{"same cname", cname, cname, ""},
+		{"other ttl", aws.NewResourceRecordSet("app.example.com", route53.RRTypeCname, 60, []string{lb}), cname, "ttl 60 instead of 300"},
+		{"other target", aws.NewResourceRecordSet("app.example.com", route53.RRTypeCname, 60, []string{"old.example.net"}), cname, "values old.example.net instead of " + lb},
+		{"other type", alias, cname, "ALIAS A instead of CNAME"},
+		{"alias without ttl", alias, alias, ""},
+	}
+	for _, test := range tests {
+		if difference := driftDifference(test.live, test.desired); difference != test.difference {
+			t.Errorf("%s: got %q, want %q", test.name, difference, test.difference)
+		}
+	}
+}
+
+func TestDetectDrift(t *testing.T) {
+	const lb = "lb.example.net"
+	tests := []struct {
+		name     string
+		fix      bool
+		existing []*route53.ResourceRecordSet
+		drifted  []DriftedRecord
+		ttl      int64
+	}{
+		{
+			name: "in sync",
+			existing: []*route53.ResourceRecordSet{
+				aws.NewResourceRecordSet("app.example.com", route53.RRTypeCname, 300, []string{lb}),
+				ownershipRecordSet("app.example.com", "ci", "service/default/app"),
+			},
+			drifted: []DriftedRecord{},
+			ttl:     300,
+		},
+		{
+			name: "drift is reported",
+			existing: []*route53.ResourceRecordSet{
+				aws.NewResourceRecordSet("app.example.com", route53.RRTypeCname, 60, []string{lb}),
+				ownershipRecordSet("app.example.com", "ci", "service/default/app"),
+			},
+			drifted: []DriftedRecord{{Host: "app.example.com", Zone: "example.com", Resource: "service/default/app", Difference: "ttl 60 instead of 300", Result: "detected"}},
+			ttl:     60,
+		},
+		{
+			name: "drift is fixed",
+			fix:  true,
+			existing: []*route53.ResourceRecordSet{
+				aws.NewResourceRecordSet("app.example.com", route53.RRTypeCname, 60, []string{lb}),
+				ownershipRecordSet("app.example.com", "ci", "service/default/app"),
+			},
+			drifted: []DriftedRecord{{Host: "app.example.com", Zone: "example.com", Resource: "service/default/app", Difference: "ttl 60 instead of 300", Result: "fixed"}},
+			ttl:     300,
+		},
+		{
+			name: "record set of another owner is not checked",
+			fix:  true,
+			existing: []*route53.ResourceRecordSet{
+				aws.NewResourceRecordSet("app.example.com", route53.RRTypeCname, 60, []string{lb}),
+				ownershipRecordSet("app.example.com", "other", "service/default/app"),
+			},
+			drifted: []DriftedRecord{},
+			ttl:     60,
+		},
+	}
+	for _, test := range tests {
+		c, zones, cleanup := newZoneFileController(t, "ci")
+		if test.fix {
+			c.EnableDriftFix()
+		}
+		writeRecordSets(t, zones, test.existing...)
+		c.addReference("app.example.com", newStatusSource(t, c, "app"), loadBalancer{dnsName: lb})
+
+		if drifted := c.DetectDrift(); !reflect.DeepEqual(drifted, test.drifted) {
+			t.Errorf("%s: got %+v, want %+v", test.name, drifted, test.drifted)
+		}
+		live, err := c.liveRecordSet("app.example.com", "example.com", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if *live.TTL != test.ttl {
+			t.Errorf("%s: got ttl %d after drift detection, want %d", test.name, *live.TTL, test.ttl)
+		}
+		cleanup()
+	}
+}
@@ -0,0 +1,139 @@
+package controller
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"github.com/go-kit/kit/log/level"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// DynamicController handles the events of a source which is a custom resource read with the dynamic client
+type DynamicController struct {
+	*Controller
+	// human readable name of the resource used in logs, e.g. HTTP route
+	name string
+	// return the hosts of the resource
+	hosts func(sourceObj *unstructured.Unstructured) []string
+	// return the load balancer the hosts of the resource point to
+	target func(sourceObj *unstructured.Unstructured) loadBalancer
+	// fields besides the hosts and annotations whose changes may change the target
+	targetFields []string
+	// called with the new target when the target of a resource changed, may be nil
+	targetChanged func(sourceObj *unstructured.Unstructured, target loadBalancer)
+}
+
+// Create will do something when a resource is beeing created
+func (d *DynamicController) Create(obj interface{}) {
+	level.Debug(d.logger).Log("msg", "Called function: Create")
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	defer d.track("create")()
+	sourceObj := obj.(*unstructured.Unstructured)
+
+	if isR53Resource(sourceObj) {
+		level.Info(d.logger).Log("msg", "Creation of a "+d.name+" detected", "resource", sourceKey(sourceObj))
+
+		d.createDynamicRecordSet(sourceObj, d.target(sourceObj))
+	}
+}
+
+// Update will do something when a resource is beeing updated
+func (d *DynamicController) Update(oldobj interface{}, newobj interface{}) {
+	newObj := newobj.(*unstructured.Unstructured)
+	oldObj := oldobj.(*unstructured.Unstructured)
+
+	level.Debug(d.logger).Log("msg", "Called function: Update")
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	defer d.track("update")()
+	d.updateHeldDeletions(newObj)
+
+	if d.noDifference(oldObj, newObj) {
+		level.Debug(d.logger).Log("msg", "Skipping automatically updated "+d.name, "resource", sourceKey(newObj))
+		return
+	}
+
+	oldTarget, newTarget := d.target(oldObj), d.target(newObj)
+	if oldTarget != newTarget && d.targetChanged != nil {
+		d.targetChanged(newObj, newTarget)
+	}
+
+	isOldR53 := isR53Resource(oldObj)
+	isR53 := isR53Resource(newObj)
+
+	if isOldR53 {
+		var hosts []string
+		if isR53 {
+			// hosts still present in the new resource are replaced by createDynamicRecordSet
+			hosts = d.removedHosts(oldObj, d.hosts(oldObj), d.hosts(newObj))
+		} else {
+			hosts = d.validHosts("delete", oldObj, d.hosts(oldObj))
+		}
+		level.Info(d.logger).Log("msg", "Update of a "+d.name+" detected, the removed hosts will be deleted.", "resource", sourceKey(oldObj))
+
+		d.deleteRecordSet(oldObj, hosts)
+	}
+
+	if isR53 {
+		level.Info(d.logger).Log("msg", "Update of a "+d.name+" detected, the new one will be created.", "resource", sourceKey(newObj))
+
+		d.createDynamicRecordSet(newObj, newTarget)
+	}
+}
+
+// Delete will do something when a resource is beeing deleted
+func (d *DynamicController) Delete(obj interface{}) {
+	level.Debug(d.logger).Log("msg", "Called function: Delete")
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	defer d.track("delete")()
+	sourceObj := obj.(*unstructured.Unstructured)
+
+	if isR53Resource(sourceObj) {
+		level.Info(d.logger).Log("msg", "Deletion of a "+d.name+" detected", "resource", sourceKey(sourceObj))
+
+		d.deleteRecordSet(sourceObj, d.validHosts("delete", sourceObj, d.hosts(sourceObj)))
+		d.forgetStatus(sourceObj)
+	}
+}
+
+// create the record sets of the resource once its target has been provisioned
+func (d *DynamicController) createDynamicRecordSet(sourceObj *unstructured.Unstructured, target loadBalancer) {
+	if target.dnsName == "" {
+		level.Info(d.logger).Log("msg", "The "+d.name+" has no load balancer hostname yet. Skipping creation/updating!", "resource", sourceKey(sourceObj))
+		return
+	}
+	d.createRecordSet(sourceObj, d.validHosts("create", sourceObj, d.hosts(sourceObj)), target)
+}
+
+// are the two resources same regarding their hosts, annotations and target fields?
+func (d *DynamicController) noDifference(oldObj *unstructured.Unstructured, newObj *unstructured.Unstructured) bool {
+	newHosts, _ := json.Marshal(d.hosts(newObj))
+	oldHosts, _ := json.Marshal(d.hosts(oldObj))
+	if string(newHosts) != string(oldHosts) ||
+		newObj.GetAnnotations()["ingress.net/route53"] != oldObj.GetAnnotations()["ingress.net/route53"] ||
+		newObj.GetAnnotations()[allowMassDeletionAnnotation] != oldObj.GetAnnotations()[allowMassDeletionAnnotation] {
+		return false
+	}
+	newFields, _ := json.Marshal(nestedFields(newObj, d.targetFields))
+	oldFields, _ := json.Marshal(nestedFields(oldObj, d.targetFields))
+	return string(newFields) == string(oldFields)
+}
+
+// return the values of the given dot separated fields of the resource
+func nestedFields(sourceObj *unstructured.Unstructured, fields []string) []interface{} {
+	var values []interface{}
+	for _, field := range fields {
+		value, _, _ := unstructured.NestedFieldNoCopy(sourceObj.Object, strings.Split(field, ".")...)
+		values = append(values, value)
+	}
+	return values
+}
+
+// check if the record sets of the resource are managed
+func isR53Resource(sourceObj *unstructured.Unstructured) bool {
+	isR53, _ := strconv.ParseBool(sourceObj.GetAnnotations()["ingress.net/route53"])
+	return isR53
+}
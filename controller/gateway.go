@@ -0,0 +1,172 @@
+package controller
+
+import (
+	"net"
+
+	"github.com/go-kit/kit/log/level"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/tools/cache"
+)
+
+// API group of the Gateway API resources
+const gatewayGroup = "gateway.networking.k8s.io"
+
+var (
+	// GatewayResource is the Gateway API resource of gateways
+	GatewayResource = schema.GroupVersionResource{Group: gatewayGroup, Version: "v1", Resource: "gateways"}
+	// HTTPRouteResource is the Gateway API resource of HTTP routes
+	HTTPRouteResource = schema.GroupVersionResource{Group: gatewayGroup, Version: "v1", Resource: "httproutes"}
+)
+
+// HTTPRoutes returns the handler for events of HTTP routes, which shares the host references with the other sources
+func (c *Controller) HTTPRoutes() *DynamicController {
+	return &DynamicController{
+		Controller:   c,
+		name:         "HTTP route",
+		hosts:        httpRouteHosts,
+		target:       c.httpRouteTarget,
+		targetFields: []string{"spec.parentRefs"},
+	}
+}
+
+// Gateways returns the handler for events of gateways, which shares the host references with the other sources.
+// If refreshRoutes is true, the record sets of the HTTP routes of a gateway follow changes of its address.
+func (c *Controller) Gateways(refreshRoutes bool) *DynamicController {
+	gateways := &DynamicController{
+		Controller:   c,
+		name:         "gateway",
+		hosts:        gatewayHosts,
+		target:       gatewayTarget,
+		targetFields: []string{"status.addresses"},
+	}
+	if refreshRoutes {
+		gateways.targetChanged = c.refreshGatewayRoutes
+	}
+	return gateways
+}
+
+// UseGatewayLister sets the cache of the gateways whose addresses are the targets of HTTP routes,
+// it is required before events of HTTP routes are handled
+func (c *Controller) UseGatewayLister(gateways cache.GenericLister) {
+	c.gateways = gateways
+}
+
+// UseHTTPRouteLister sets the cache of the HTTP routes, it is required before events of gateways are handled
+// if the HTTP routes follow their gateways
+func (c *Controller) UseHTTPRouteLister(httpRoutes cache.GenericLister) {
+	c.httpRoutes = httpRoutes
+}
+
+// point the record sets of all published HTTP routes attached to the gateway to its new address
+func (c *Controller) refreshGatewayRoutes(gatewayObj *unstructured.Unstructured, target loadBalancer) {
+	if target.dnsName == "" {
+		return
+	}
+	routes, err := c.httpRoutes.List(labels.Everything())
+	if err != nil {
+		level.Error(c.logger).Log("msg", "Could not list HTTP routes of gateway", "err", err.Error(), "resource", sourceKey(gatewayObj))
+		return
+	}
+	for _, obj := range routes {
+		routeObj := obj.(*unstructured.Unstructured)
+		if !isR53Resource(routeObj) || !attachedTo(routeObj, gatewayObj) {
+			continue
+		}
+		level.Info(c.logger).Log("msg", "Address of gateway changed, updating HTTP route", "resource", sourceKey(routeObj), "gateway", sourceKey(gatewayObj))
+		c.createRecordSet(routeObj, c.validHosts("create", routeObj, httpRouteHosts(routeObj)), target)
+	}
+}
+
+// return the load balancer of the first parent gateway of the HTTP route which has a hostname address
+func (c *Controller) httpRouteTarget(routeObj *unstructured.Unstructured) loadBalancer {
+	parentRefs, _, _ := unstructured.NestedSlice(routeObj.Object, "spec", "parentRefs")
+	for _, parentRef := range parentRefs {
+		namespace, name, ok := gatewayRef(parentRef, routeObj.GetNamespace())
+		if !ok {
+			continue
+		}
+		obj, err := c.gateways.ByNamespace(namespace).Get(name)
+		if err != nil {
+			level.Warn(c.logger).Log("msg", "Could not get parent gateway of HTTP route", "err", err.Error(), "gateway", namespace+"/"+name, "resource", sourceKey(routeObj))
+			continue
+		}
+		if target := gatewayTarget(obj.(*unstructured.Unstructured)); target.dnsName != "" {
+			return target
+		}
+	}
+	return loadBalancer{}
+}
+
+// return the load balancer of the gateway given by the first hostname in its status addresses
+func gatewayTarget(gatewayObj *unstructured.Unstructured) loadBalancer {
+	addresses, _, _ := unstructured.NestedSlice(gatewayObj.Object, "status", "addresses")
+	for _, address := range addresses {
+		fields, ok := address.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		addressType, _ := fields["type"].(string)
+		value, _ := fields["value"].(string)
+		if value == "" || addressType != "" && addressType != "Hostname" || net.ParseIP(value) != nil {
+			continue
+		}
+		return loadBalancer{dnsName: value}
+	}
+	return loadBalancer{}
+}
+
+// return namespace and name of a parent reference pointing to a gateway
+func gatewayRef(parentRef interface{}, routeNamespace string) (string, string, bool) {
+	fields, ok := parentRef.(map[string]interface{})
+	if !ok {
+		return "", "", false
+	}
+	group, ok := fields["group"].(string)
+	if !ok {
+		group = gatewayGroup
+	}
+	kind, ok := fields["kind"].(string)
+	if !ok {
+		kind = "Gateway"
+	}
+	namespace, ok := fields["namespace"].(string)
+	if !ok {
+		namespace = routeNamespace
+	}
+	name, _ := fields["name"].(string)
+	return namespace, name, group == gatewayGroup && kind == "Gateway" && name != ""
+}
+
+// check if the HTTP route references the gateway as parent
+func attachedTo(routeObj *unstructured.Unstructured, gatewayObj *unstructured.Unstructured) bool {
+	parentRefs, _, _ := unstructured.NestedSlice(routeObj.Object, "spec", "parentRefs")
+	for _, parentRef := range parentRefs {
+		namespace, name, ok := gatewayRef(parentRef, routeObj.GetNamespace())
+		if ok && namespace == gatewayObj.GetNamespace() && name == gatewayObj.GetName() {
+			return true
+		}
+	}
+	return false
+}
+
+// return the hosts of a HTTP route
+func httpRouteHosts(routeObj *unstructured.Unstructured) []string {
+	hosts, _, _ := unstructured.NestedStringSlice(routeObj.Object, "spec", "hostnames")
+	return hosts
+}
+
+// return the listener hostnames of a gateway
+func gatewayHosts(gatewayObj *unstructured.Unstructured) []string {
+	var hosts []string
+	listeners, _, _ := unstructured.NestedSlice(gatewayObj.Object, "spec", "listeners")
+	for _, listener := range listeners {
+		if fields, ok := listener.(map[string]interface{}); ok {
+			if hostname, ok := fields["hostname"].(string); ok {
+				hosts = append(hosts, hostname)
+			}
+		}
+	}
+	return hosts
+}
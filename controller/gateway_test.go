@@ -0,0 +1,124 @@
+package controller
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/service/route53"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/tools/cache"
+)
+
+// return a published gateway in the namespace default with the hostname address, none if it is empty
+func newGateway(name, address string) *unstructured.Unstructured {
+	gatewayObj := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": gatewayGroup + "/v1",
+		"kind":       "Gateway",
+		"metadata": map[string]interface{}{
+			"namespace":   "default",
+			"name":        name,
+			"uid":         name,
+			"annotations": map[string]interface{}{"ingress.net/route53": "true"},
+		},
+	}}
+	if address != "" {
+		unstructured.SetNestedSlice(gatewayObj.Object, []interface{}{map[string]interface{}{"type": "Hostname", "value": address}}, "status", "addresses")
+	}
+	return gatewayObj
+}
+
+// return a published HTTP route in the namespace default attached to the gateway
+func newHTTPRoute(name, gateway string, hosts ...interface{}) *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": gatewayGroup + "/v1",
+		"kind":       "HTTPRoute",
+		"metadata": map[string]interface{}{
+			"namespace":   "default",
+			"name":        name,
+			"uid":         name,
+			"annotations": map[string]interface{}{"ingress.net/route53": "true"},
+		},
+		"spec": map[string]interface{}{
+			"parentRefs": []interface{}{map[string]interface{}{"name": gateway}},
+			"hostnames":  hosts,
+		},
+	}}
+}
+
+// create a controller for HTTP routes and gateways whose caches hold the given resources, which are also served by the
+// fake dynamic api for their status
+func newGatewayController(t *testing.T, objs ...*unstructured.Unstructured) (c *Controller, gateways cache.Indexer, zones func() map[string]bool, cleanup func()) {
+	c, provider, cleanup := newZoneFileController(t, "")
+	gateways = cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	routes := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	var runtimeObjs []runtime.Object
+	for _, obj := range objs {
+		runtimeObjs = append(runtimeObjs, obj)
+		if obj.GetKind() == "Gateway" {
+			gateways.Add(obj)
+		} else {
+			routes.Add(obj)
+		}
+	}
+	c.UseDynamicClient(dynamicfake.NewSimpleDynamicClient(runtime.NewScheme(), runtimeObjs...))
+	c.UseGatewayLister(cache.NewGenericLister(gateways, GatewayResource.GroupResource()))
+	c.UseHTTPRouteLister(cache.NewGenericLister(routes, HTTPRouteResource.GroupResource()))
+	return c, gateways, func() map[string]bool { return zoneRecordSets(t, provider) }, cleanup
+}
+
+func TestHTTPRouteTarget(t *testing.T) {
+	tests := []struct {
+		name     string
+		gateways []*unstructured.Unstructured
+		dnsName  string
+	}{
+		{"address of the gateway", []*unstructured.Unstructured{newGateway("public", "gw-123.eu-central-1.elb.amazonaws.com")}, "gw-123.eu-central-1.elb.amazonaws.com"},
+		{"gateway without address", []*unstructured.Unstructured{newGateway("public", "")}, ""},
+		{"gateway not cached", nil, ""},
+	}
+	for _, test := range tests {
+		c, _, _, cleanup := newGatewayController(t, test.gateways...)
+		if target := c.httpRouteTarget(newHTTPRoute("app", "public", "app.example.com")); target.dnsName != test.dnsName {
+			t.Errorf("%s: got %q, want %q", test.name, target.dnsName, test.dnsName)
+		}
+		cleanup()
+	}
+}
+
+func TestHTTPRouteEvents(t *testing.T) {
+	gatewayObj := newGateway("public", "gw-123.eu-central-1.elb.amazonaws.com")
+	routeObj := newHTTPRoute("app", "public", "app.example.com")
+	c, gateways, zones, cleanup := newGatewayController(t, gatewayObj, routeObj)
+	defer cleanup()
+	target := func() string {
+		live, err := c.liveRecordSet("app.example.com", "example.com", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if live == nil || *live.Type != route53.RRTypeCname {
+			return ""
+		}
+		return describeTarget(live)
+	}
+
+	c.HTTPRoutes().Create(routeObj)
+	if got := target(); got != "gw-123.eu-central-1.elb.amazonaws.com" {
+		t.Fatalf("got target %q after creation, want the address of the gateway", got)
+	}
+
+	// the routes follow a changed address of their gateway
+	changedObj := newGateway("public", "gw-456.eu-central-1.elb.amazonaws.com")
+	gateways.Update(changedObj)
+	c.Gateways(true).Update(gatewayObj, changedObj)
+	if got := target(); got != "gw-456.eu-central-1.elb.amazonaws.com" {
+		t.Fatalf("got target %q after the gateway changed, want its new address", got)
+	}
+
+	// the record set of a route deleted after its gateway is deleted by the published target
+	gateways.Delete(changedObj)
+	c.HTTPRoutes().Delete(routeObj)
+	if sets := zones(); len(sets) != 0 {
+		t.Errorf("got %v after the route was deleted, want no record sets", sets)
+	}
+}
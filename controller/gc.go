@@ -0,0 +1,212 @@
+package controller
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/route53"
+	"github.com/dbsystel/AmazonRoute53-ingress-controller/aws"
+	"github.com/dbsystel/AmazonRoute53-ingress-controller/metrics"
+	"github.com/dbsystel/AmazonRoute53-ingress-controller/provider"
+	"github.com/go-kit/kit/log/level"
+	"k8s.io/client-go/tools/cache"
+)
+
+// OrphanedRecord is a host whose ownership record carries our owner ID, but which is not referenced by any resource
+type OrphanedRecord struct {
+	Host string `json:"host"`
+	Zone string `json:"zone"`
+	// resource of the ownership record, which does not exist anymore
+	Resource string `json:"resource"`
+	// one of: deleted, dry_run, held, skipped, error
+	Result string `json:"result"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// RunGarbageCollection deletes orphaned record sets periodically until stopCh is closed.
+// The first pass starts one interval after all informers have synced, so the handlers have seen all resources.
+func (c *Controller) RunGarbageCollection(interval time.Duration, stopCh <-chan struct{}, wg *sync.WaitGroup, synced ...cache.InformerSynced) {
+	defer wg.Done()
+
+	if !cache.WaitForCacheSync(stopCh, synced...) {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			c.CollectGarbage()
+		case <-stopCh:
+			return
+		}
+	}
+}
+
+// CollectGarbage deletes the record sets of all hosts in all hosted zones, whose ownership record carries our owner ID,
+// but which are no longer referenced by any resource, e.g. because it was deleted while the controller was down.
+// Deletions honour the allowlist, the policy, the mass deletion limits and the dry run mode.
+// The hosted zones are read without blocking event handlers, only each orphan is deleted while events are not handled.
+func (c *Controller) CollectGarbage() []OrphanedRecord {
+	defer observe("gc")()
+	c.configMutex.RLock()
+	defer c.configMutex.RUnlock()
+
+	orphans := []OrphanedRecord{}
+	if c.config.OwnerID == "" {
+		level.Warn(c.logger).Log("msg", "Garbage collection skipped, orphaned record sets are only found by ownership records written with --owner-id")
+		return orphans
+	}
+
+	level.Info(c.logger).Log("msg", "Garbage collection started", "ownerID", c.config.OwnerID)
+	c.mutex.Lock()
+	inUse := c.namesInUse()
+	c.mutex.Unlock()
+	for _, zone := range c.managedZones() {
+		roleARN := c.zoneRole(zone.Name)
+		resourceRecordSets, err := c.provider.ListRecordSets(zone.ID, roleARN)
+		if err != nil {
+			c.handleError(err)
+			continue
+		}
+		for _, resourceRecordSet := range resourceRecordSets {
+			name := aws.NormalizeName(*resourceRecordSet.Name)
+			if *resourceRecordSet.Type != route53.RRTypeTxt || !strings.HasPrefix(name, ownershipPrefix) {
+				continue
+			}
+			ownership := parseOwnership(resourceRecordSet)
+			if ownership == nil || ownership["owner"] != c.config.OwnerID {
+				continue
+			}
+			host := ownedHost(name)
+			if inUse[host] {
+				continue
+			}
+
+			orphan, ok := c.collectOrphan(host, zone, roleARN, ownership["resource"], resourceRecordSet, resourceRecordSets)
+			if !ok {
+				continue
+			}
+			metrics.OrphanedRecords.WithLabelValues(orphan.Result).Inc()
+			orphans = append(orphans, orphan)
+		}
+	}
+	level.Info(c.logger).Log("msg", "Garbage collection finished", "orphans", len(orphans))
+	return orphans
+}
+
+// delete the orphaned host while events are not handled, unless it was referenced since the zone was read.
+// Returns false if the host is in use again.
+func (c *Controller) collectOrphan(host string, zone provider.Zone, roleARN, resource string, ownershipRecordSet *route53.ResourceRecordSet, resourceRecordSets []*route53.ResourceRecordSet) (OrphanedRecord, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.nameInUse(host) {
+		return OrphanedRecord{}, false
+	}
+	return c.deleteOrphan(host, zone, roleARN, resource, ownershipRecordSet, resourceRecordSets), true
+}
+
+// delete the record set of the orphaned host together with its ownership record
+func (c *Controller) deleteOrphan(host string, zone provider.Zone, roleARN, resource string, ownershipRecordSet *route53.ResourceRecordSet, resourceRecordSets []*route53.ResourceRecordSet) OrphanedRecord {
+	orphan := OrphanedRecord{Host: host, Zone: zone.Name, Resource: resource}
+	skip := func(result, reason string) OrphanedRecord {
+		orphan.Result = result
+		orphan.Reason = reason
+		level.Warn(c.logger).Log("msg", "Orphaned record set not deleted", "reason", reason, "hostName", host, "resource", resource)
+		return orphan
+	}
+
+	if allowed, rule := c.config.Allowlist.Evaluate(host); !allowed {
+		return skip("skipped", "not allowed by rule "+rule)
+	}
+	if c.policy != PolicySync {
+		return skip("skipped", "policy "+c.policy+" never deletes record sets")
+	}
+	if strings.HasPrefix(resource, "dnsrecord."+dnsRecordGroup+"/") {
+		// the type of the record set declared by a DNSRecord is unknown
+		return skip("skipped", "record sets of DNSRecords are not collected, delete them manually")
+	}
+	if allowed, _, _ := c.reserveDeletion(host, resource, false); !allowed {
+		return skip("held", "mass deletion limits exceeded")
+	}
+
+	changes := []*route53.Change{}
+	for _, resourceRecordSet := range resourceRecordSets {
+		recordType := *resourceRecordSet.Type
+		if aws.NormalizeName(*resourceRecordSet.Name) == host && resourceRecordSet.SetIdentifier == nil &&
+			(recordType == route53.RRTypeA || recordType == route53.RRTypeCname) {
+			changes = append(changes, aws.NewRecordSetChange(route53.ChangeActionDelete, resourceRecordSet))
+		}
+	}
+	changes = append(changes, aws.NewRecordSetChange(route53.ChangeActionDelete, ownershipRecordSet))
+
+	changeID, err := c.submitChanges(zone.ID, roleARN, changes)
+	if err != nil {
+		orphan.Result = "error"
+		orphan.Reason = err.Error()
+		return orphan
+	}
+	orphan.Result = "deleted"
+	if c.dryRun {
+		orphan.Result = "dry_run"
+	}
+	level.Info(c.logger).Log("msg", "Deleted orphaned record set", "changeID", changeID, "hostName", host, "resource", resource, "dryRun", c.dryRun)
+	return orphan
+}
+
+// return all zones of the provider listed with the IAM role of each zone, sorted by name
+func (c *Controller) managedZones() []provider.Zone {
+	roles := map[string]bool{"": true}
+	for _, role := range c.config.ZoneRoles {
+		roles[role] = true
+	}
+
+	var managed []provider.Zone
+	seen := make(map[string]bool)
+	for role := range roles {
+		zones, err := c.provider.Zones(role)
+		if err != nil {
+			c.handleError(err)
+			continue
+		}
+		for _, zone := range zones {
+			// a zone is only listed with its own role
+			if seen[zone.ID] || c.zoneRole(zone.Name) != role {
+				continue
+			}
+			seen[zone.ID] = true
+			managed = append(managed, zone)
+		}
+	}
+	sort.Slice(managed, func(i, j int) bool {
+		return managed[i].Name < managed[j].Name
+	})
+	return managed
+}
+
+// return the host of the ownership record name, the inverse of ownershipName
+func ownedHost(name string) string {
+	host := strings.TrimPrefix(name, ownershipPrefix)
+	if strings.HasPrefix(host, ownershipWildcard+".") {
+		host = "*" + strings.TrimPrefix(host, ownershipWildcard)
+	}
+	return host
+}
+
+// return the names of all referenced hosts and of all record sets declared by DNSRecords
+func (c *Controller) namesInUse() map[string]bool {
+	inUse := make(map[string]bool)
+	for host, references := range c.hostReferences {
+		if len(references) > 0 {
+			inUse[host] = true
+		}
+	}
+	for key := range c.records {
+		inUse[strings.SplitN(key, "/", 2)[0]] = true
+	}
+	return inUse
+}
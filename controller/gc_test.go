@@ -0,0 +1,82 @@
+package controller
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/service/route53"
+	"github.com/dbsystel/AmazonRoute53-ingress-controller/aws"
+)
+
+// return the ownership record of the host carrying the owner ID and the resource
+func ownershipRecordSet(host, ownerID, resource string) *route53.ResourceRecordSet {
+	value := "heritage=" + ownershipHeritage + ",owner=" + ownerID + ",resource=" + resource
+	return aws.NewTXTChange(route53.ChangeActionCreate, ownershipName(host), value, 300).ResourceRecordSet
+}
+
+func TestCollectGarbage(t *testing.T) {
+	c, zones, cleanup := newZoneFileController(t, "ci")
+	defer cleanup()
+
+	writeRecordSets(t, zones,
+		aws.NewResourceRecordSet("old.example.com", route53.RRTypeCname, 300, []string{"lb.example.net"}),
+		ownershipRecordSet("old.example.com", "ci", "ingress/default/old"),
+		aws.NewResourceRecordSet("app.example.com", route53.RRTypeCname, 300, []string{"lb.example.net"}),
+		ownershipRecordSet("app.example.com", "ci", "ingress/default/app"),
+		aws.NewResourceRecordSet("other.example.com", route53.RRTypeCname, 300, []string{"lb.example.net"}),
+		ownershipRecordSet("other.example.com", "other", "ingress/default/other"),
+		aws.NewResourceRecordSet("manual.example.com", route53.RRTypeCname, 300, []string{"lb.example.net"}),
+	)
+	c.addReference("app.example.com", newDeletionSource("app", false), loadBalancer{name: "app"})
+
+	orphans := c.CollectGarbage()
+	want := []OrphanedRecord{{Host: "old.example.com", Zone: "example.com", Resource: "ingress/default/old", Result: "deleted"}}
+	if !reflect.DeepEqual(orphans, want) {
+		t.Errorf("got orphans %+v, want %+v", orphans, want)
+	}
+
+	kept := map[string]bool{
+		"_r53-ingress-owner.app.example.com/TXT":   true,
+		"_r53-ingress-owner.other.example.com/TXT": true,
+		"app.example.com/CNAME":                    true,
+		"manual.example.com/CNAME":                 true,
+		"other.example.com/CNAME":                  true,
+	}
+	if names := zoneRecordSets(t, zones); !reflect.DeepEqual(names, kept) {
+		t.Errorf("got record sets %v after garbage collection, want %v", names, kept)
+	}
+}
+
+func TestNamesInUse(t *testing.T) {
+	c, _, cleanup := newZoneFileController(t, "ci")
+	defer cleanup()
+
+	c.addReference("app.example.com", newDeletionSource("app", false), loadBalancer{name: "app"})
+	c.addReference("removed.example.com", newDeletionSource("app", false), loadBalancer{name: "app"})
+	c.removeReference("removed.example.com", newDeletionSource("app", false))
+	c.records["mail.example.com/MX"] = "dnsrecord.example.com/default/mail"
+
+	want := map[string]bool{"app.example.com": true, "mail.example.com": true}
+	if inUse := c.namesInUse(); !reflect.DeepEqual(inUse, want) {
+		t.Errorf("got %v, want %v", inUse, want)
+	}
+}
+
+func TestOwnedHost(t *testing.T) {
+	tests := []struct {
+		name string
+		host string
+	}{
+		{"_r53-ingress-owner.app.example.com", "app.example.com"},
+		{"_r53-ingress-owner._wildcard.example.com", "*.example.com"},
+		{"_r53-ingress-owner._wildcard-app.example.com", "_wildcard-app.example.com"},
+	}
+	for _, test := range tests {
+		if host := ownedHost(test.name); host != test.host {
+			t.Errorf("ownedHost(%q) = %q, want %q", test.name, host, test.host)
+		}
+		if test.host[0] != '_' && ownershipName(test.host) != test.name {
+			t.Errorf("ownershipName(%q) = %q, want %q", test.host, ownershipName(test.host), test.name)
+		}
+	}
+}
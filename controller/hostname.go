@@ -0,0 +1,141 @@
+package controller
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/dbsystel/AmazonRoute53-ingress-controller/metrics"
+	"github.com/go-kit/kit/log/level"
+	"golang.org/x/net/idna"
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	maxHostLength  = 253
+	maxLabelLength = 63
+)
+
+// return the normalized form of the host: lowercased, without trailing dot and with IDN labels converted to punycode.
+// An error is returned if the result is no valid host name according to RFC 1123.
+func normalizeHost(host string) (string, error) {
+	return normalizeName(host, false)
+}
+
+// return the normalized form of a record name like normalizeHost, underscores are allowed if underscore is true,
+// e.g. for TXT records like _acme-challenge.example.com
+func normalizeName(host string, underscore bool) (string, error) {
+	name := strings.TrimSuffix(strings.TrimSpace(host), ".")
+	wildcard := strings.HasPrefix(name, "*.")
+	name = strings.TrimPrefix(name, "*.")
+
+	if strings.IndexFunc(name, func(char rune) bool { return char > unicode.MaxASCII }) >= 0 {
+		var err error
+		if name, err = idna.Lookup.ToASCII(name); err != nil {
+			return "", fmt.Errorf("invalid international domain name: %v", err)
+		}
+	}
+	name = strings.ToLower(name)
+	if wildcard {
+		name = "*." + name
+	}
+
+	if len(name) > maxHostLength {
+		return "", fmt.Errorf("host name is longer than %d characters", maxHostLength)
+	}
+	for i, label := range strings.Split(name, ".") {
+		if wildcard && i == 0 {
+			continue
+		}
+		if err := validateLabel(label, underscore); err != nil {
+			return "", err
+		}
+	}
+	return name, nil
+}
+
+// check a single label of a host name against RFC 1123, optionally allowing underscores
+func validateLabel(label string, underscore bool) error {
+	if len(label) == 0 {
+		return fmt.Errorf("host name contains an empty label")
+	}
+	if len(label) > maxLabelLength {
+		return fmt.Errorf("label %q is longer than %d characters", label, maxLabelLength)
+	}
+	if label[0] == '-' || label[len(label)-1] == '-' {
+		return fmt.Errorf("label %q must not start or end with a hyphen", label)
+	}
+	for _, char := range label {
+		if !(char >= 'a' && char <= 'z' || char >= '0' && char <= '9' || char == '-' || underscore && char == '_') {
+			return fmt.Errorf("label %q contains invalid character %q", label, char)
+		}
+	}
+	return nil
+}
+
+// return the normalized hosts without duplicates and the errors of the invalid hosts
+func normalizeHosts(rawHosts []string) ([]string, map[string]error) {
+	var hosts []string
+	invalid := make(map[string]error)
+	seen := make(map[string]bool)
+	for _, rawHost := range rawHosts {
+		if rawHost == "" {
+			continue
+		}
+		host, err := normalizeHost(rawHost)
+		if err != nil {
+			invalid[rawHost] = err
+			continue
+		}
+		if !seen[host] {
+			seen[host] = true
+			hosts = append(hosts, host)
+		}
+	}
+	return hosts, invalid
+}
+
+// return the normalized hosts of the resource without duplicates, invalid hosts are skipped and reported.
+// With an owner ID, hosts whose ownership record name would be too long are invalid as well.
+func (c *Controller) validHosts(operation string, sourceObj recordSource, rawHosts []string) []string {
+	hosts, invalid := normalizeHosts(rawHosts)
+	if c.config.OwnerID != "" {
+		var owned []string
+		for _, host := range hosts {
+			if err := validateOwnershipName(host); err != nil {
+				invalid[host] = err
+				continue
+			}
+			owned = append(owned, host)
+		}
+		hosts = owned
+	}
+	for rawHost, err := range invalid {
+		metrics.HostsInvalid.WithLabelValues(operation).Inc()
+		level.Warn(c.logger).Log("msg", "Provided host "+rawHost+" is no valid DNS name. Skipping!", "err", err.Error(), "hostName", rawHost, "resource", sourceKey(sourceObj))
+		if operation != "delete" {
+			c.recorder.Eventf(sourceObj, corev1.EventTypeWarning, "InvalidHost", "Host %q is no valid DNS name (%v), no Route53 record set will be created", rawHost, err)
+			message := err.Error()
+			c.setHostStatus(sourceObj, rawHost, func(status *hostStatus) {
+				status.Invalid = message
+			})
+		}
+	}
+	return hosts
+}
+
+// return the valid hosts of the old resource which are not part of the new one, both normalized the same way
+func (c *Controller) removedHosts(oldObj recordSource, oldRawHosts, newRawHosts []string) []string {
+	newHosts, _ := normalizeHosts(newRawHosts)
+	remaining := make(map[string]bool)
+	for _, host := range newHosts {
+		remaining[host] = true
+	}
+	var removed []string
+	for _, host := range c.validHosts("delete", oldObj, oldRawHosts) {
+		if !remaining[host] {
+			removed = append(removed, host)
+		}
+	}
+	return removed
+}
@@ -0,0 +1,131 @@
+package controller
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNormalizeName(t *testing.T) {
+	tests := []struct {
+		host       string
+		underscore bool
+		normalized string
+		valid      bool
+	}{
+		{"App.Example.COM", false, "app.example.com", true},
+		{" app.example.com. ", false, "app.example.com", true},
+		{"*.Example.com", false, "*.example.com", true},
+		{"bücher.example.com", false, "xn--bcher-kva.example.com", true},
+		{"_acme-challenge.example.com", true, "_acme-challenge.example.com", true},
+		{"_acme-challenge.example.com", false, "", false},
+		{"app..example.com", false, "", false},
+		{"-app.example.com", false, "", false},
+		{"app-.example.com", false, "", false},
+		{"app_1.example.com", false, "", false},
+		{"a.*.example.com", false, "", false},
+		{"app.example.com/path", false, "", false},
+		{strings.Repeat("a", 64) + ".example.com", false, "", false},
+		{strings.Repeat("a", 63) + ".example.com", false, strings.Repeat("a", 63) + ".example.com", true},
+		{strings.Repeat("a.", 127) + "com", false, "", false},
+	}
+	for _, test := range tests {
+		normalized, err := normalizeName(test.host, test.underscore)
+		if (err == nil) != test.valid || normalized != test.normalized {
+			t.Errorf("normalizeName(%q, %v) = %q, %v, want %q, valid %v", test.host, test.underscore, normalized, err, test.normalized, test.valid)
+		}
+	}
+}
+
+func TestValidateLabel(t *testing.T) {
+	tests := []struct {
+		label      string
+		underscore bool
+		valid      bool
+	}{
+		{"app", false, true},
+		{"app-1", false, true},
+		{"1app", false, true},
+		{"", false, false},
+		{"-app", false, false},
+		{"app-", false, false},
+		{"App", false, false},
+		{"_dmarc", false, false},
+		{"_dmarc", true, true},
+		{strings.Repeat("a", 63), false, true},
+		{strings.Repeat("a", 64), false, false},
+	}
+	for _, test := range tests {
+		if err := validateLabel(test.label, test.underscore); (err == nil) != test.valid {
+			t.Errorf("validateLabel(%q, %v) = %v, want valid %v", test.label, test.underscore, err, test.valid)
+		}
+	}
+}
+
+func TestNormalizeHosts(t *testing.T) {
+	hosts, invalid := normalizeHosts([]string{"App.example.com", "", "app.example.com.", "bad_host.example.com", "b.example.com"})
+	if want := []string{"app.example.com", "b.example.com"}; !reflect.DeepEqual(hosts, want) {
+		t.Errorf("got hosts %q, want %q", hosts, want)
+	}
+	if _, ok := invalid["bad_host.example.com"]; !ok || len(invalid) != 1 {
+		t.Errorf("got invalid hosts %v, want bad_host.example.com", invalid)
+	}
+}
+
+func TestRemovedHosts(t *testing.T) {
+	c := newDeletionGuardController(DeletionLimits{}, 0)
+	source := newDeletionSource("app", false)
+	tests := []struct {
+		name    string
+		old     []string
+		new     []string
+		removed []string
+	}{
+		{"unchanged", []string{"a.example.com"}, []string{"a.example.com"}, nil},
+		{"case and trailing dot", []string{"A.example.com."}, []string{"a.example.com"}, nil},
+		{"removed host", []string{"a.example.com", "b.example.com"}, []string{"a.example.com"}, []string{"b.example.com"}},
+		{"invalid old host", []string{"bad_host.example.com", "b.example.com"}, nil, []string{"b.example.com"}},
+		{"invalid new host", []string{"a.example.com"}, []string{"bad_host.example.com"}, []string{"a.example.com"}},
+	}
+	for _, test := range tests {
+		if removed := c.removedHosts(source, test.old, test.new); !reflect.DeepEqual(removed, test.removed) {
+			t.Errorf("%s: got %q, want %q", test.name, removed, test.removed)
+		}
+	}
+}
+
+func TestValidHostsOwnershipName(t *testing.T) {
+	label := strings.Repeat("a", 63)
+	base := label + "." + label + "." + label + ".example.com"
+	// the ownership record of the longest host is exactly 253 characters long
+	longest := strings.Repeat("b", 30) + "." + base
+	tooLong := strings.Repeat("b", 31) + "." + base
+	tests := []struct {
+		name    string
+		ownerID string
+		host    string
+		valid   bool
+		invalid string
+	}{
+		{"longest host with owner", "ci", longest, true, ""},
+		{"too long ownership record", "ci", tooLong, false, "name of its ownership record is longer than 253 characters"},
+		{"too long ownership record of wildcard", "ci", "*." + strings.Repeat("b", 22) + "." + base, false, "name of its ownership record is longer than 253 characters"},
+		{"no ownership record without owner", "", tooLong, true, ""},
+	}
+	for _, test := range tests {
+		c, _, cleanup := newZoneFileController(t, test.ownerID)
+		service := newStatusSource(t, c, "app")
+		hosts := c.validHosts("create", service, []string{test.host})
+		if valid := len(hosts) == 1; valid != test.valid {
+			t.Errorf("%s: got valid %v, want %v", test.name, valid, test.valid)
+		}
+		var invalid string
+		if status := hostStatusOf(t, c, service, test.host); status != nil {
+			invalid = status.Invalid
+		}
+		if invalid != test.invalid {
+			t.Errorf("%s: got status %q, want %q", test.name, invalid, test.invalid)
+		}
+		cleanup()
+	}
+}
@@ -0,0 +1,206 @@
+package controller
+
+import (
+	"encoding/json"
+	"strconv"
+
+	"github.com/go-kit/kit/log/level"
+	"k8s.io/api/networking/v1beta1"
+)
+
+// ingress annotation to publish the hosts of the TLS sections, overrides --tls-hosts
+const tlsHostsAnnotation = "ingress.net/route53-tls-hosts"
+
+// Create will do something when an ingress resource is beeing created
+func (c *Controller) Create(obj interface{}) {
+	level.Debug(c.logger).Log("msg", "Called function: Create")
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	defer c.track("create")()
+	ingressObj := obj.(*v1beta1.Ingress)
+
+	r53, _ := ingressObj.Annotations["ingress.net/route53"]
+
+	isR53, _ := strconv.ParseBool(r53)
+
+	if isR53 {
+		level.Info(c.logger).Log("msg", "Creation of an ingress resource detected", "ingressName", ingressObj.Name, "ingressNamespace", ingressObj.Namespace)
+
+		c.createRecordSet(ingressObj, c.ingressHosts("create", ingressObj), ingressTarget(ingressObj))
+	}
+}
+
+// Update will do something when an ingress resource is beeing updated
+func (c *Controller) Update(oldobj interface{}, newobj interface{}) {
+	newIngressObj := newobj.(*v1beta1.Ingress)
+	oldIngressObj := oldobj.(*v1beta1.Ingress)
+
+	level.Debug(c.logger).Log("msg", "Called function: Update")
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	defer c.track("update")()
+	c.updateHeldDeletions(newIngressObj)
+
+	if c.noDifference(oldIngressObj, newIngressObj) {
+		level.Debug(c.logger).Log("msg", "Skipping automatically updated ingress", "ingressName", newIngressObj.Name, "ingressNamespace", newIngressObj.Namespace)
+		return
+	}
+
+	oldR53, _ := oldIngressObj.Annotations["ingress.net/route53"]
+	r53, _ := newIngressObj.Annotations["ingress.net/route53"]
+
+	isOldR53, _ := strconv.ParseBool(oldR53)
+	isR53, _ := strconv.ParseBool(r53)
+
+	if isOldR53 {
+		var hosts []string
+		if isR53 {
+			// hosts still present in the new ingress resource are replaced by createRecordSet
+			hosts = c.removedHosts(oldIngressObj, c.rawHosts(oldIngressObj), c.rawHosts(newIngressObj))
+		} else {
+			hosts = c.ingressHosts("delete", oldIngressObj)
+		}
+		level.Info(c.logger).Log("msg", "Update of an ingress resource detected, the removed hosts will be deleted.", "ingressName", oldIngressObj.Name, "ingressNamespace", oldIngressObj.Namespace)
+
+		c.deleteRecordSet(oldIngressObj, hosts)
+	}
+
+	if isR53 {
+		level.Info(c.logger).Log("msg", "Update of an ingress resource detected, the new one will be created.", "ingressName", newIngressObj.Name, "ingressNamespace", newIngressObj.Namespace)
+
+		c.createRecordSet(newIngressObj, c.ingressHosts("create", newIngressObj), ingressTarget(newIngressObj))
+	}
+}
+
+// Delete will do something when an ingress resource is beeing deleted
+func (c *Controller) Delete(obj interface{}) {
+	level.Debug(c.logger).Log("msg", "Called function: Delete")
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	defer c.track("delete")()
+	ingressObj := obj.(*v1beta1.Ingress)
+
+	r53, _ := ingressObj.Annotations["ingress.net/route53"]
+
+	isR53, _ := strconv.ParseBool(r53)
+
+	if isR53 {
+		level.Info(c.logger).Log("msg", "Deletion of an ingress resource detected", "ingressName", ingressObj.Name, "ingressNamespace", ingressObj.Namespace)
+
+		c.deleteRecordSet(ingressObj, c.ingressHosts("delete", ingressObj))
+		c.forgetStatus(ingressObj)
+	}
+}
+
+// are the two ingress resources same?
+func (c *Controller) noDifference(newIngressObj *v1beta1.Ingress, oldIngressObj *v1beta1.Ingress) bool {
+	if len(newIngressObj.Spec.Rules) != len(oldIngressObj.Spec.Rules) {
+		newIngressObjContent, _ := json.Marshal(newIngressObj.Spec.Rules)
+		oldIngressObjContent, _ := json.Marshal(oldIngressObj.Spec.Rules)
+		level.Debug(c.logger).Log(
+			"msg", "length of ingressObj spec rules are different",
+			"newIngressObjSpecRulesLength", len(newIngressObj.Spec.Rules),
+			"oldIngressObjSpecRulesLength", len(oldIngressObj.Spec.Rules),
+			"newIngressObjSpecRulesContent", string(newIngressObjContent),
+			"oldIngressObjSpecRulesContent", string(oldIngressObjContent),
+		)
+		return false
+	}
+	for i, ingressRule := range newIngressObj.Spec.Rules {
+		if ingressRule.Host != oldIngressObj.Spec.Rules[i].Host {
+			level.Debug(c.logger).Log(
+				"msg", "ingressObj spec rules host names are different",
+				"newIngressObjHostName", ingressRule.Host,
+				"oldIngressObjHostName", oldIngressObj.Spec.Rules[i].Host,
+			)
+			return false
+		}
+	}
+
+	if newIngressObj.Annotations["ingress.net/route53"] != oldIngressObj.Annotations["ingress.net/route53"] {
+		level.Debug(c.logger).Log(
+			"msg", "ingressObj annotations route53 are different",
+			"newIngressObjAnnotation", newIngressObj.Annotations["ingress.net/route53"],
+			"oldIngressObjAnnotation", oldIngressObj.Annotations["ingress.net/route53"],
+		)
+		return false
+	}
+
+	if newIngressObj.Annotations["ingress.net/load-balancer-name"] != oldIngressObj.Annotations["ingress.net/load-balancer-name"] {
+		level.Debug(c.logger).Log(
+			"msg", "ingressObj annotations load-balancer-name are different",
+			"newIngressObjAnnotation", newIngressObj.Annotations["ingress.net/load-balancer-name"],
+			"oldIngressObjAnnotation", oldIngressObj.Annotations["ingress.net/load-balancer-name"],
+		)
+		return false
+	}
+
+	if newIngressObj.Annotations["ingress.net/alias"] != oldIngressObj.Annotations["ingress.net/alias"] {
+		level.Debug(c.logger).Log(
+			"msg", "ingressObj annotations alias are different",
+			"newIngressObjAnnotation", newIngressObj.Annotations["ingress.net/alias"],
+			"oldIngressObjAnnotation", oldIngressObj.Annotations["ingress.net/alias"],
+		)
+		return false
+	}
+
+	if newIngressObj.Annotations[tlsHostsAnnotation] != oldIngressObj.Annotations[tlsHostsAnnotation] {
+		level.Debug(c.logger).Log(
+			"msg", "ingressObj annotations tls-hosts are different",
+			"newIngressObjAnnotation", newIngressObj.Annotations[tlsHostsAnnotation],
+			"oldIngressObjAnnotation", oldIngressObj.Annotations[tlsHostsAnnotation],
+		)
+		return false
+	}
+
+	newTLSHosts, _ := json.Marshal(newIngressObj.Spec.TLS)
+	oldTLSHosts, _ := json.Marshal(oldIngressObj.Spec.TLS)
+	if string(newTLSHosts) != string(oldTLSHosts) {
+		level.Debug(c.logger).Log(
+			"msg", "ingressObj spec tls are different",
+			"newIngressObjSpecTLSContent", string(newTLSHosts),
+			"oldIngressObjSpecTLSContent", string(oldTLSHosts),
+		)
+		return false
+	}
+
+	return true
+}
+
+// return the hosts of the ingress resource: the hosts of its rules and, if enabled, of its TLS sections
+func (c *Controller) rawHosts(ingressObj *v1beta1.Ingress) []string {
+	var hosts []string
+	for _, ingressRule := range ingressObj.Spec.Rules {
+		hosts = append(hosts, ingressRule.Host)
+	}
+	if c.includeTLSHosts(ingressObj) {
+		for _, tls := range ingressObj.Spec.TLS {
+			hosts = append(hosts, tls.Hosts...)
+		}
+	}
+	return hosts
+}
+
+// return the load balancer given by the annotation of the ingress resource
+func ingressTarget(ingressObj *v1beta1.Ingress) loadBalancer {
+	return loadBalancer{name: ingressObj.Annotations["ingress.net/load-balancer-name"]}
+}
+
+// return the normalized hosts of the ingress resource without duplicates, invalid hosts are skipped and reported
+func (c *Controller) ingressHosts(operation string, ingressObj *v1beta1.Ingress) []string {
+	return c.validHosts(operation, ingressObj, c.rawHosts(ingressObj))
+}
+
+// check if the TLS hosts of the ingress resource are published, its annotation takes precedence over --tls-hosts
+func (c *Controller) includeTLSHosts(ingressObj *v1beta1.Ingress) bool {
+	value, ok := ingressObj.Annotations[tlsHostsAnnotation]
+	if !ok {
+		return c.tlsHosts
+	}
+	include, err := strconv.ParseBool(value)
+	if err != nil {
+		level.Warn(c.logger).Log("msg", "Ignoring invalid annotation "+tlsHostsAnnotation, "value", value, "ingressName", ingressObj.Name, "ingressNamespace", ingressObj.Namespace)
+		return c.tlsHosts
+	}
+	return include
+}
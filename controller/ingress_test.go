@@ -0,0 +1,279 @@
+package controller
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"k8s.io/api/networking/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// ingressEvent is an event of the named ingress resource, an update without load balancer unpublishes the resource
+type ingressEvent struct {
+	action           string
+	name             string
+	loadBalancerName string
+	hosts            []string
+}
+
+// create the published ingress resource pointing to the named load balancer in the fake kubernetes api
+func newIngress(t *testing.T, c *Controller, name, loadBalancerName string, hosts ...string) *v1beta1.Ingress {
+	ingressObj := &v1beta1.Ingress{ObjectMeta: metav1.ObjectMeta{
+		Namespace:   "default",
+		Name:        name,
+		UID:         types.UID(name),
+		Annotations: map[string]string{"ingress.net/route53": "true", "ingress.net/load-balancer-name": loadBalancerName},
+	}}
+	for _, host := range hosts {
+		ingressObj.Spec.Rules = append(ingressObj.Spec.Rules, v1beta1.IngressRule{Host: host})
+	}
+	created, err := c.kclient.NetworkingV1beta1().Ingresses("default").Create(ingressObj)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return created
+}
+
+// handle the events in order, resources are created one second after each other
+func handleIngressEvents(t *testing.T, c *Controller, events []ingressEvent) {
+	ingresses := make(map[string]*v1beta1.Ingress)
+	for i, event := range events {
+		switch event.action {
+		case "create":
+			ingressObj := newIngress(t, c, event.name, event.loadBalancerName, event.hosts...)
+			ingressObj.CreationTimestamp = metav1.NewTime(time.Unix(int64(i), 0))
+			ingresses[event.name] = ingressObj
+			c.Create(ingressObj)
+		case "update":
+			oldIngressObj := ingresses[event.name]
+			ingressObj := oldIngressObj.DeepCopy()
+			ingressObj.Annotations["ingress.net/load-balancer-name"] = event.loadBalancerName
+			if event.loadBalancerName == "" {
+				ingressObj.Annotations["ingress.net/route53"] = "false"
+			}
+			ingressObj.Spec.Rules = nil
+			for _, host := range event.hosts {
+				ingressObj.Spec.Rules = append(ingressObj.Spec.Rules, v1beta1.IngressRule{Host: host})
+			}
+			ingresses[event.name] = ingressObj
+			c.Update(oldIngressObj, ingressObj)
+		case "delete":
+			c.Delete(ingresses[event.name])
+			delete(ingresses, event.name)
+		}
+	}
+}
+
+// return the status of the host written to the named ingress resource, nil if there is none
+func ingressStatusOf(t *testing.T, c *Controller, name, host string) *hostStatus {
+	writeStatuses(t, c)
+	current, err := c.kclient.NetworkingV1beta1().Ingresses("default").Get(name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	statuses := map[string]*hostStatus{}
+	if value, ok := current.Annotations[statusAnnotation]; ok {
+		if err := json.Unmarshal([]byte(value), &statuses); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return statuses[host]
+}
+
+func TestIngressEvents(t *testing.T) {
+	const (
+		a = "a-123.eu-central-1.elb.amazonaws.com"
+		b = "b-456.eu-central-1.elb.amazonaws.com"
+	)
+	tests := []struct {
+		name   string
+		events []ingressEvent
+		want   map[string]string
+		// ingress resource which has to report a conflict for app.example.com
+		conflicting string
+	}{
+		{
+			name:   "create",
+			events: []ingressEvent{{"create", "app", "a", []string{"app.example.com", "www.example.com"}}},
+			want:   map[string]string{"app.example.com/CNAME": a, "www.example.com/CNAME": a},
+		},
+		{
+			name: "update replaces removed hosts",
+			events: []ingressEvent{
+				{"create", "app", "a", []string{"app.example.com", "old.example.com"}},
+				{"update", "app", "a", []string{"app.example.com", "new.example.com"}},
+			},
+			want: map[string]string{"app.example.com/CNAME": a, "new.example.com/CNAME": a},
+		},
+		{
+			name: "update repoints to another load balancer",
+			events: []ingressEvent{
+				{"create", "app", "a", []string{"app.example.com"}},
+				{"update", "app", "b", []string{"app.example.com"}},
+			},
+			want: map[string]string{"app.example.com/CNAME": b},
+		},
+		{
+			name: "update unpublishes",
+			events: []ingressEvent{
+				{"create", "app", "a", []string{"app.example.com"}},
+				{"update", "app", "", []string{"app.example.com"}},
+			},
+			want: map[string]string{},
+		},
+		{
+			name: "delete",
+			events: []ingressEvent{
+				{"create", "app", "a", []string{"app.example.com"}},
+				{"delete", "app", "a", []string{"app.example.com"}},
+			},
+			want: map[string]string{},
+		},
+		{
+			name: "shared host is kept until the last resource is deleted",
+			events: []ingressEvent{
+				{"create", "app", "a", []string{"app.example.com"}},
+				{"create", "copy", "a", []string{"app.example.com"}},
+				{"delete", "app", "a", []string{"app.example.com"}},
+			},
+			want: map[string]string{"app.example.com/CNAME": a},
+		},
+		{
+			name: "shared host is deleted with the last resource",
+			events: []ingressEvent{
+				{"create", "app", "a", []string{"app.example.com"}},
+				{"create", "copy", "a", []string{"app.example.com"}},
+				{"delete", "app", "a", []string{"app.example.com"}},
+				{"delete", "copy", "a", []string{"app.example.com"}},
+			},
+			want: map[string]string{},
+		},
+		{
+			name: "older resource owns a conflicting host",
+			events: []ingressEvent{
+				{"create", "older", "a", []string{"app.example.com"}},
+				{"create", "newer", "b", []string{"app.example.com"}},
+			},
+			want:        map[string]string{"app.example.com/CNAME": a},
+			conflicting: "newer",
+		},
+		{
+			name: "host is handed over when its owner is deleted",
+			events: []ingressEvent{
+				{"create", "older", "a", []string{"app.example.com"}},
+				{"create", "newer", "b", []string{"app.example.com"}},
+				{"delete", "older", "a", []string{"app.example.com"}},
+			},
+			want: map[string]string{"app.example.com/CNAME": b},
+		},
+		{
+			name: "host is handed over when its owner removes it",
+			events: []ingressEvent{
+				{"create", "older", "a", []string{"app.example.com", "www.example.com"}},
+				{"create", "newer", "b", []string{"app.example.com"}},
+				{"update", "older", "a", []string{"www.example.com"}},
+			},
+			want: map[string]string{"app.example.com/CNAME": b, "www.example.com/CNAME": a},
+		},
+		{
+			name: "owner is kept when the conflicting resource is deleted",
+			events: []ingressEvent{
+				{"create", "older", "a", []string{"app.example.com"}},
+				{"create", "newer", "b", []string{"app.example.com"}},
+				{"delete", "newer", "b", []string{"app.example.com"}},
+			},
+			want: map[string]string{"app.example.com/CNAME": a},
+		},
+	}
+	for _, test := range tests {
+		c, zones, cleanup := newZoneFileController(t, "")
+		c.UseStaticLoadBalancers(map[string]string{"a": a, "b": b})
+		handleIngressEvents(t, c, test.events)
+
+		if got := zoneTargets(t, zones); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: got record sets %v, want %v", test.name, got, test.want)
+		}
+		if test.conflicting != "" {
+			if status := ingressStatusOf(t, c, test.conflicting, "app.example.com"); status == nil || status.Conflict == "" {
+				t.Errorf("%s: got status %+v of %s, want a conflict", test.name, status, test.conflicting)
+			}
+		}
+		cleanup()
+	}
+}
+
+func TestDeleteAfterLoadBalancerChanged(t *testing.T) {
+	tests := []struct {
+		name    string
+		changed map[string]string
+	}{
+		{"unchanged", map[string]string{"lb": "old-123.eu-central-1.elb.amazonaws.com"}},
+		{"recreated", map[string]string{"lb": "new-456.eu-central-1.elb.amazonaws.com"}},
+		{"deleted", map[string]string{}},
+	}
+	for _, test := range tests {
+		// without owner ID only record sets pointing to the published target are deleted
+		c, zones, cleanup := newZoneFileController(t, "")
+		c.UseStaticLoadBalancers(map[string]string{"lb": "old-123.eu-central-1.elb.amazonaws.com"})
+		ingressObj := newIngress(t, c, "app", "lb", "app.example.com")
+		c.Create(ingressObj)
+		if sets := zoneRecordSets(t, zones); !sets["app.example.com/CNAME"] {
+			t.Fatalf("%s: got %v after creation, want the CNAME record set", test.name, sets)
+		}
+
+		c.UseStaticLoadBalancers(test.changed)
+		c.Delete(ingressObj)
+		if sets := zoneRecordSets(t, zones); len(sets) != 0 {
+			t.Errorf("%s: got %v after deletion, want no record sets", test.name, sets)
+		}
+		cleanup()
+	}
+}
+
+func TestTLSHosts(t *testing.T) {
+	const a = "a-123.eu-central-1.elb.amazonaws.com"
+	rule := map[string]string{"app.example.com/CNAME": a}
+	all := map[string]string{"app.example.com/CNAME": a, "secure.example.com/CNAME": a}
+	tests := []struct {
+		name     string
+		tlsHosts bool
+		// value of the annotation ingress.net/route53-tls-hosts, none if empty
+		annotation string
+		// value of the annotation after an update, unchanged if empty
+		updated string
+		want    map[string]string
+	}{
+		{"disabled", false, "", "", rule},
+		{"enabled by flag", true, "", "", all},
+		{"enabled by annotation", false, "true", "", all},
+		{"disabled by annotation", true, "false", "", rule},
+		{"invalid annotation falls back to the flag", true, "yes please", "", all},
+		{"enabling annotation publishes the TLS hosts", false, "false", "true", all},
+		{"disabling annotation deletes the TLS hosts", false, "true", "false", rule},
+	}
+	for _, test := range tests {
+		c, zones, cleanup := newZoneFileController(t, "")
+		c.tlsHosts = test.tlsHosts
+		c.UseStaticLoadBalancers(map[string]string{"a": a})
+		ingressObj := newIngress(t, c, "app", "a", "app.example.com")
+		// the host of the rule is repeated in the TLS section, it is published once
+		ingressObj.Spec.TLS = []v1beta1.IngressTLS{{Hosts: []string{"app.example.com", "secure.example.com"}}}
+		if test.annotation != "" {
+			ingressObj.Annotations[tlsHostsAnnotation] = test.annotation
+		}
+		c.Create(ingressObj)
+		if test.updated != "" {
+			updatedObj := ingressObj.DeepCopy()
+			updatedObj.Annotations[tlsHostsAnnotation] = test.updated
+			c.Update(ingressObj, updatedObj)
+		}
+
+		if got := zoneTargets(t, zones); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: got record sets %v, want %v", test.name, got, test.want)
+		}
+		cleanup()
+	}
+}
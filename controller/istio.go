@@ -0,0 +1,289 @@
+package controller
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/go-kit/kit/log/level"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+// API group of the Istio networking resources
+const istioGroup = "networking.istio.io"
+
+var (
+	// IstioGatewayResource is the Istio resource of gateways
+	IstioGatewayResource = schema.GroupVersionResource{Group: istioGroup, Version: "v1beta1", Resource: "gateways"}
+	// VirtualServiceResource is the Istio resource of virtual services
+	VirtualServiceResource = schema.GroupVersionResource{Group: istioGroup, Version: "v1beta1", Resource: "virtualservices"}
+)
+
+// IstioGateways returns the handler for events of Istio gateways, which shares the host references with the other sources.
+// If refreshVirtualServices is true, the record sets of the virtual services of a gateway follow changes of its selector.
+func (c *Controller) IstioGateways(refreshVirtualServices bool) *DynamicController {
+	gateways := &DynamicController{
+		Controller:   c,
+		name:         "Istio gateway",
+		hosts:        istioGatewayHosts,
+		target:       c.istioGatewayTarget,
+		targetFields: []string{"spec.selector"},
+	}
+	if refreshVirtualServices {
+		gateways.targetChanged = c.refreshVirtualServices
+	}
+	return gateways
+}
+
+// VirtualServices returns the handler for events of Istio virtual services, which shares the host references with the other sources
+func (c *Controller) VirtualServices() *DynamicController {
+	return &DynamicController{
+		Controller:   c,
+		name:         "virtual service",
+		hosts:        virtualServiceHosts,
+		target:       c.virtualServiceTarget,
+		targetFields: []string{"spec.gateways"},
+	}
+}
+
+// UseServiceLister sets the cache of the services whose load balancers are the targets of Istio gateways,
+// it is required before events of Istio gateways or virtual services are handled
+func (c *Controller) UseServiceLister(services corelisters.ServiceLister) {
+	c.services = services
+}
+
+// UseIstioGatewayLister sets the cache of the Istio gateways, which is used instead of reading them from the API
+// and to repoint the published gateways after the load balancer of their service changed
+func (c *Controller) UseIstioGatewayLister(gateways cache.GenericLister) {
+	c.istioGateways = gateways
+}
+
+// UseVirtualServiceLister sets the cache of the virtual services, it is required before events of Istio gateways are handled
+// if the virtual services follow their gateways
+func (c *Controller) UseVirtualServiceLister(virtualServices cache.GenericLister) {
+	c.virtualServices = virtualServices
+}
+
+// IstioServiceController handles events of services whose load balancers are the targets of Istio gateways
+type IstioServiceController struct {
+	*Controller
+}
+
+// IstioServices returns the handler for events of services, which repoints the published Istio gateways and virtual services
+// after the load balancer of their service changed
+func (c *Controller) IstioServices() *IstioServiceController {
+	return &IstioServiceController{Controller: c}
+}
+
+// Create ignores new services, they get a load balancer by a later update
+func (s *IstioServiceController) Create(obj interface{}) {
+}
+
+// Update repoints the Istio gateways and virtual services selected by the service if its load balancer or selector changed
+func (s *IstioServiceController) Update(oldobj interface{}, newobj interface{}) {
+	newServiceObj := newobj.(*corev1.Service)
+	oldServiceObj := oldobj.(*corev1.Service)
+	if serviceTarget(oldServiceObj) == serviceTarget(newServiceObj) && labels.Equals(oldServiceObj.Spec.Selector, newServiceObj.Spec.Selector) {
+		return
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	defer s.track("update")()
+
+	selected := func(gatewayObj *unstructured.Unstructured) bool {
+		selector, _, _ := unstructured.NestedStringMap(gatewayObj.Object, "spec", "selector")
+		return len(selector) > 0 && (selects(oldServiceObj.Spec.Selector, selector) || selects(newServiceObj.Spec.Selector, selector))
+	}
+	if s.istioGateways != nil {
+		gateways, _ := s.istioGateways.List(labels.Everything())
+		for _, obj := range gateways {
+			gatewayObj := obj.(*unstructured.Unstructured)
+			if !isR53Resource(gatewayObj) || !selected(gatewayObj) {
+				continue
+			}
+			level.Info(s.logger).Log("msg", "Load balancer of service changed, updating Istio gateway", "resource", sourceKey(gatewayObj), "service", oldServiceObj.Namespace+"/"+oldServiceObj.Name)
+			s.createRecordSet(gatewayObj, s.validHosts("create", gatewayObj, istioGatewayHosts(gatewayObj)), s.istioGatewayTarget(gatewayObj))
+		}
+	}
+	if s.virtualServices != nil {
+		virtualServices, _ := s.virtualServices.List(labels.Everything())
+		for _, obj := range virtualServices {
+			virtualServiceObj := obj.(*unstructured.Unstructured)
+			if !isR53Resource(virtualServiceObj) || !s.boundToSelected(virtualServiceObj, selected) {
+				continue
+			}
+			level.Info(s.logger).Log("msg", "Load balancer of service changed, updating virtual service", "resource", sourceKey(virtualServiceObj), "service", oldServiceObj.Namespace+"/"+oldServiceObj.Name)
+			s.createRecordSet(virtualServiceObj, s.validHosts("create", virtualServiceObj, virtualServiceHosts(virtualServiceObj)), s.virtualServiceTarget(virtualServiceObj))
+		}
+	}
+}
+
+// Delete ignores deleted services, the record sets are kept like those of deleted load balancers
+func (s *IstioServiceController) Delete(obj interface{}) {
+}
+
+// check if the virtual service is bound to an Istio gateway for which selected returns true
+func (c *Controller) boundToSelected(virtualServiceObj *unstructured.Unstructured, selected func(*unstructured.Unstructured) bool) bool {
+	gateways, _, _ := unstructured.NestedStringSlice(virtualServiceObj.Object, "spec", "gateways")
+	for _, gateway := range gateways {
+		namespace, name, ok := istioGatewayRef(gateway, virtualServiceObj.GetNamespace())
+		if !ok {
+			continue
+		}
+		if gatewayObj, err := c.istioGateway(namespace, name); err == nil && selected(gatewayObj) {
+			return true
+		}
+	}
+	return false
+}
+
+// point the record sets of all published virtual services bound to the gateway to its new load balancer
+func (c *Controller) refreshVirtualServices(gatewayObj *unstructured.Unstructured, target loadBalancer) {
+	if target.dnsName == "" {
+		return
+	}
+	virtualServices, err := c.virtualServices.List(labels.Everything())
+	if err != nil {
+		level.Error(c.logger).Log("msg", "Could not list virtual services of Istio gateway", "err", err.Error(), "resource", sourceKey(gatewayObj))
+		return
+	}
+	for _, obj := range virtualServices {
+		virtualServiceObj := obj.(*unstructured.Unstructured)
+		if !isR53Resource(virtualServiceObj) || !boundTo(virtualServiceObj, gatewayObj) {
+			continue
+		}
+		level.Info(c.logger).Log("msg", "Load balancer of Istio gateway changed, updating virtual service", "resource", sourceKey(virtualServiceObj), "gateway", sourceKey(gatewayObj))
+		c.createRecordSet(virtualServiceObj, c.validHosts("create", virtualServiceObj, virtualServiceHosts(virtualServiceObj)), target)
+	}
+}
+
+// return the load balancer of the first Istio gateway of the virtual service which has one
+func (c *Controller) virtualServiceTarget(virtualServiceObj *unstructured.Unstructured) loadBalancer {
+	gateways, _, _ := unstructured.NestedStringSlice(virtualServiceObj.Object, "spec", "gateways")
+	for _, gateway := range gateways {
+		namespace, name, ok := istioGatewayRef(gateway, virtualServiceObj.GetNamespace())
+		if !ok {
+			continue
+		}
+		gatewayObj, err := c.istioGateway(namespace, name)
+		if err != nil {
+			level.Warn(c.logger).Log("msg", "Could not get Istio gateway of virtual service", "err", err.Error(), "gateway", namespace+"/"+name, "resource", sourceKey(virtualServiceObj))
+			continue
+		}
+		if target := c.istioGatewayTarget(gatewayObj); target.dnsName != "" {
+			return target
+		}
+	}
+	return loadBalancer{}
+}
+
+// return the Istio gateway from the cache if there is one, otherwise from the API
+func (c *Controller) istioGateway(namespace, name string) (*unstructured.Unstructured, error) {
+	if c.istioGateways != nil {
+		obj, err := c.istioGateways.ByNamespace(namespace).Get(name)
+		if err != nil {
+			return nil, err
+		}
+		return obj.(*unstructured.Unstructured), nil
+	}
+	return c.dclient.Resource(IstioGatewayResource).Namespace(namespace).Get(name, metav1.GetOptions{})
+}
+
+// return the load balancer of the first service of type LoadBalancer, ordered by namespace and name, selecting the workload
+// of the Istio gateway, e.g. the istio-ingressgateway service
+func (c *Controller) istioGatewayTarget(gatewayObj *unstructured.Unstructured) loadBalancer {
+	selector, _, _ := unstructured.NestedStringMap(gatewayObj.Object, "spec", "selector")
+	if len(selector) == 0 {
+		return loadBalancer{}
+	}
+	services, err := c.services.List(labels.Everything())
+	if err != nil {
+		level.Error(c.logger).Log("msg", "Could not list services to find the load balancer of Istio gateway", "err", err.Error(), "resource", sourceKey(gatewayObj))
+		return loadBalancer{}
+	}
+	sort.Slice(services, func(i, j int) bool {
+		return services[i].Namespace+"/"+services[i].Name < services[j].Namespace+"/"+services[j].Name
+	})
+	for _, serviceObj := range services {
+		if serviceObj.Spec.Type != corev1.ServiceTypeLoadBalancer || !selects(serviceObj.Spec.Selector, selector) {
+			continue
+		}
+		if target := serviceTarget(serviceObj); target.dnsName != "" {
+			return target
+		}
+	}
+	return loadBalancer{}
+}
+
+// check if the service selector selects every workload selected by the gateway selector
+func selects(serviceSelector map[string]string, gatewaySelector map[string]string) bool {
+	for key, value := range gatewaySelector {
+		if serviceSelector[key] != value {
+			return false
+		}
+	}
+	return true
+}
+
+// return namespace and name of a gateway referenced by a virtual service, the reserved gateway mesh is skipped
+func istioGatewayRef(gateway string, virtualServiceNamespace string) (string, string, bool) {
+	if gateway == "" || gateway == "mesh" {
+		return "", "", false
+	}
+	if parts := strings.SplitN(gateway, "/", 2); len(parts) == 2 {
+		return parts[0], parts[1], true
+	}
+	return virtualServiceNamespace, gateway, true
+}
+
+// check if the virtual service is bound to the Istio gateway
+func boundTo(virtualServiceObj *unstructured.Unstructured, gatewayObj *unstructured.Unstructured) bool {
+	gateways, _, _ := unstructured.NestedStringSlice(virtualServiceObj.Object, "spec", "gateways")
+	for _, gateway := range gateways {
+		namespace, name, ok := istioGatewayRef(gateway, virtualServiceObj.GetNamespace())
+		if ok && namespace == gatewayObj.GetNamespace() && name == gatewayObj.GetName() {
+			return true
+		}
+	}
+	return false
+}
+
+// return the hosts of the servers of an Istio gateway without their namespace prefix, the catch-all host * is skipped
+func istioGatewayHosts(gatewayObj *unstructured.Unstructured) []string {
+	var hosts []string
+	servers, _, _ := unstructured.NestedSlice(gatewayObj.Object, "spec", "servers")
+	for _, server := range servers {
+		fields, ok := server.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		serverHosts, _, _ := unstructured.NestedStringSlice(fields, "hosts")
+		for _, host := range serverHosts {
+			if parts := strings.SplitN(host, "/", 2); len(parts) == 2 {
+				host = parts[1]
+			}
+			if host != "*" {
+				hosts = append(hosts, host)
+			}
+		}
+	}
+	return hosts
+}
+
+// return the fully qualified hosts of a virtual service, short names of services within the mesh are skipped
+func virtualServiceHosts(virtualServiceObj *unstructured.Unstructured) []string {
+	var hosts []string
+	virtualServiceHosts, _, _ := unstructured.NestedStringSlice(virtualServiceObj.Object, "spec", "hosts")
+	for _, host := range virtualServiceHosts {
+		if host != "*" && strings.Contains(host, ".") {
+			hosts = append(hosts, host)
+		}
+	}
+	return hosts
+}
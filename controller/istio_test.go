@@ -0,0 +1,184 @@
+package controller
+
+import (
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+// return a service of the given type selecting istio=ingressgateway with the load balancer hostname, none if it is empty
+func newIstioService(namespace, name string, serviceType corev1.ServiceType, hostname string) *corev1.Service {
+	serviceObj := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name},
+		Spec:       corev1.ServiceSpec{Type: serviceType, Selector: map[string]string{"istio": "ingressgateway", "app": name}},
+	}
+	if hostname != "" {
+		serviceObj.Status.LoadBalancer.Ingress = []corev1.LoadBalancerIngress{{Hostname: hostname}}
+	}
+	return serviceObj
+}
+
+// return a published Istio gateway in the namespace default with the selector and the hosts of one server
+func newIstioGateway(name string, selector map[string]interface{}, hosts ...interface{}) *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": istioGroup + "/v1beta1",
+		"kind":       "Gateway",
+		"metadata": map[string]interface{}{
+			"namespace":   "default",
+			"name":        name,
+			"uid":         name,
+			"annotations": map[string]interface{}{"ingress.net/route53": "true"},
+		},
+		"spec": map[string]interface{}{
+			"selector": selector,
+			"servers":  []interface{}{map[string]interface{}{"hosts": hosts}},
+		},
+	}}
+}
+
+// return a published virtual service in the namespace default bound to the gateways
+func newVirtualService(name string, gateways []interface{}, hosts ...interface{}) *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": istioGroup + "/v1beta1",
+		"kind":       "VirtualService",
+		"metadata": map[string]interface{}{
+			"namespace":   "default",
+			"name":        name,
+			"uid":         name,
+			"annotations": map[string]interface{}{"ingress.net/route53": "true"},
+		},
+		"spec": map[string]interface{}{"gateways": gateways, "hosts": hosts},
+	}}
+}
+
+// create a controller for Istio resources whose caches hold the given services, gateways and virtual services, which are
+// also served by the fake dynamic api for their status
+func newIstioController(t *testing.T, services []*corev1.Service, objs ...*unstructured.Unstructured) (c *Controller, serviceIndexer cache.Indexer, zones func() map[string]string, cleanup func()) {
+	c, provider, cleanup := newZoneFileController(t, "")
+	serviceIndexer = cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	for _, serviceObj := range services {
+		serviceIndexer.Add(serviceObj)
+	}
+	gateways := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	virtualServices := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	var runtimeObjs []runtime.Object
+	for _, obj := range objs {
+		runtimeObjs = append(runtimeObjs, obj)
+		if obj.GetKind() == "Gateway" {
+			gateways.Add(obj)
+		} else {
+			virtualServices.Add(obj)
+		}
+	}
+	c.UseDynamicClient(dynamicfake.NewSimpleDynamicClient(runtime.NewScheme(), runtimeObjs...))
+	c.UseServiceLister(corelisters.NewServiceLister(serviceIndexer))
+	c.UseIstioGatewayLister(cache.NewGenericLister(gateways, IstioGatewayResource.GroupResource()))
+	c.UseVirtualServiceLister(cache.NewGenericLister(virtualServices, VirtualServiceResource.GroupResource()))
+	return c, serviceIndexer, func() map[string]string { return zoneTargets(t, provider) }, cleanup
+}
+
+func TestIstioGatewayTarget(t *testing.T) {
+	const (
+		a = "a-123.elb.eu-central-1.amazonaws.com"
+		b = "b-456.elb.eu-central-1.amazonaws.com"
+	)
+	ingressGateway := map[string]interface{}{"istio": "ingressgateway"}
+	tests := []struct {
+		name     string
+		services []*corev1.Service
+		selector map[string]interface{}
+		dnsName  string
+	}{
+		{"selected load balancer", []*corev1.Service{newIstioService("istio-system", "public", corev1.ServiceTypeLoadBalancer, a)}, ingressGateway, a},
+		{"first by namespace and name", []*corev1.Service{newIstioService("istio-system", "second", corev1.ServiceTypeLoadBalancer, b), newIstioService("istio-system", "first", corev1.ServiceTypeLoadBalancer, a)}, ingressGateway, a},
+		{"selector of the gateway narrowed down", []*corev1.Service{newIstioService("istio-system", "first", corev1.ServiceTypeLoadBalancer, a), newIstioService("istio-system", "second", corev1.ServiceTypeLoadBalancer, b)}, map[string]interface{}{"istio": "ingressgateway", "app": "second"}, b},
+		{"pending load balancer is skipped", []*corev1.Service{newIstioService("istio-system", "first", corev1.ServiceTypeLoadBalancer, ""), newIstioService("istio-system", "second", corev1.ServiceTypeLoadBalancer, b)}, ingressGateway, b},
+		{"other service type", []*corev1.Service{newIstioService("istio-system", "public", corev1.ServiceTypeNodePort, a)}, ingressGateway, ""},
+		{"not selected", []*corev1.Service{newIstioService("istio-system", "public", corev1.ServiceTypeLoadBalancer, a)}, map[string]interface{}{"istio": "egressgateway"}, ""},
+		{"gateway without selector", []*corev1.Service{newIstioService("istio-system", "public", corev1.ServiceTypeLoadBalancer, a)}, map[string]interface{}{}, ""},
+	}
+	for _, test := range tests {
+		c, _, _, cleanup := newIstioController(t, test.services)
+		if target := c.istioGatewayTarget(newIstioGateway("public", test.selector, "app.example.com")); target.dnsName != test.dnsName {
+			t.Errorf("%s: got %q, want %q", test.name, target.dnsName, test.dnsName)
+		}
+		cleanup()
+	}
+}
+
+func TestVirtualServiceTarget(t *testing.T) {
+	const a = "a-123.elb.eu-central-1.amazonaws.com"
+	services := []*corev1.Service{newIstioService("istio-system", "public", corev1.ServiceTypeLoadBalancer, a)}
+	gateways := []*unstructured.Unstructured{
+		newIstioGateway("public", map[string]interface{}{"istio": "ingressgateway"}, "*"),
+		newIstioGateway("internal", map[string]interface{}{"istio": "internalgateway"}, "*"),
+	}
+	tests := []struct {
+		name     string
+		gateways []interface{}
+		dnsName  string
+	}{
+		{"gateway of the namespace", []interface{}{"public"}, a},
+		{"gateway with namespace", []interface{}{"default/public"}, a},
+		{"first gateway with a load balancer", []interface{}{"mesh", "missing", "internal", "public"}, a},
+		{"mesh only", []interface{}{"mesh"}, ""},
+		{"gateway of another namespace", []interface{}{"other/public"}, ""},
+	}
+	for _, test := range tests {
+		c, _, _, cleanup := newIstioController(t, services, gateways...)
+		if target := c.virtualServiceTarget(newVirtualService("app", test.gateways, "app.example.com")); target.dnsName != test.dnsName {
+			t.Errorf("%s: got %q, want %q", test.name, target.dnsName, test.dnsName)
+		}
+		cleanup()
+	}
+}
+
+func TestIstioHosts(t *testing.T) {
+	gatewayObj := newIstioGateway("public", nil, "*", "default/app.example.com", "*/www.example.com", "api.example.com")
+	if got, want := istioGatewayHosts(gatewayObj), []string{"app.example.com", "www.example.com", "api.example.com"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got gateway hosts %v, want %v", got, want)
+	}
+	virtualServiceObj := newVirtualService("app", nil, "*", "reviews", "reviews.default.svc.cluster.local", "app.example.com")
+	if got, want := virtualServiceHosts(virtualServiceObj), []string{"reviews.default.svc.cluster.local", "app.example.com"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got virtual service hosts %v, want %v", got, want)
+	}
+}
+
+func TestIstioEvents(t *testing.T) {
+	const (
+		a = "a-123.elb.eu-central-1.amazonaws.com"
+		b = "b-456.elb.eu-central-1.amazonaws.com"
+	)
+	serviceObj := newIstioService("istio-system", "public", corev1.ServiceTypeLoadBalancer, a)
+	gatewayObj := newIstioGateway("public", map[string]interface{}{"istio": "ingressgateway"}, "gateway.example.com")
+	virtualServiceObj := newVirtualService("app", []interface{}{"public"}, "app.example.com")
+	c, services, zones, cleanup := newIstioController(t, []*corev1.Service{serviceObj}, gatewayObj, virtualServiceObj)
+	defer cleanup()
+
+	c.IstioGateways(true).Create(gatewayObj)
+	c.VirtualServices().Create(virtualServiceObj)
+	if got, want := zones(), map[string]string{"gateway.example.com/CNAME": a, "app.example.com/CNAME": a}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("got record sets %v after creation, want %v", got, want)
+	}
+
+	// the gateway and its virtual services follow the load balancer of the selected service
+	changedObj := newIstioService("istio-system", "public", corev1.ServiceTypeLoadBalancer, b)
+	services.Update(changedObj)
+	c.IstioServices().Update(serviceObj, changedObj)
+	if got, want := zones(), map[string]string{"gateway.example.com/CNAME": b, "app.example.com/CNAME": b}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got record sets %v after the load balancer changed, want %v", got, want)
+	}
+
+	c.VirtualServices().Delete(virtualServiceObj)
+	c.IstioGateways(true).Delete(gatewayObj)
+	if got := zones(); len(got) != 0 {
+		t.Errorf("got record sets %v after deletion, want none", got)
+	}
+}
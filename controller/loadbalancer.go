@@ -0,0 +1,177 @@
+package controller
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/dbsystel/AmazonRoute53-ingress-controller/aws"
+	"github.com/dbsystel/AmazonRoute53-ingress-controller/metrics"
+	"github.com/go-kit/kit/log/level"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+// loadBalancerAttributes are the resolved attributes of a load balancer given by its name
+type loadBalancerAttributes struct {
+	dnsName      string
+	hostedZoneID string
+	// true if the load balancer was not found by its first resolution or the last refresh, the attributes are the last
+	// known ones then. Missing load balancers are pending: they are looked up again by every refresh.
+	missing bool
+}
+
+// EnableLoadBalancerCache makes the controller resolve the attributes of each load balancer given by its name only once.
+// The cached attributes are only updated by RefreshLoadBalancers.
+func (c *Controller) EnableLoadBalancerCache() {
+	c.loadBalancers = make(map[string]loadBalancerAttributes)
+}
+
+// RunLoadBalancerWatcher refreshes the cached load balancer attributes periodically until stopCh is closed
+func (c *Controller) RunLoadBalancerWatcher(interval time.Duration, stopCh <-chan struct{}, wg *sync.WaitGroup, synced ...cache.InformerSynced) {
+	defer wg.Done()
+
+	if !cache.WaitForCacheSync(stopCh, synced...) {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			c.RefreshLoadBalancers()
+		case <-stopCh:
+			return
+		}
+	}
+}
+
+// RefreshLoadBalancers resolves all cached load balancers again. If a load balancer was recreated with another DNS name
+// or hosted zone or is found for the first time, the record sets of all hosts pointing to it are updated. A deleted
+// load balancer is reported on the resources of its hosts, its record sets are kept until it is recreated. Load balancers
+// which are no longer referenced are dropped from the cache. The load balancers are looked up without blocking event handlers.
+func (c *Controller) RefreshLoadBalancers() {
+	defer observe("loadbalancers")()
+
+	c.loadBalancersMutex.Lock()
+	names := make([]string, 0, len(c.loadBalancers))
+	for name := range c.loadBalancers {
+		names = append(names, name)
+	}
+	c.loadBalancersMutex.Unlock()
+	sort.Strings(names)
+
+	for _, name := range names {
+		c.mutex.Lock()
+		referenced := len(c.loadBalancerHosts(name)) > 0
+		c.mutex.Unlock()
+		if !referenced {
+			c.loadBalancersMutex.Lock()
+			delete(c.loadBalancers, name)
+			c.loadBalancersMutex.Unlock()
+			continue
+		}
+
+		dnsName, hostedZoneID, err := aws.LookupLoadBalancer(name)
+		if err != nil {
+			c.handleError(err)
+			continue
+		}
+		c.refreshLoadBalancer(name, dnsName, hostedZoneID)
+	}
+}
+
+// update the cached attributes of the load balancer with the given name by the looked up ones, an empty DNS name
+// means that it was not found. The hosts pointing to it are updated while events are not handled.
+func (c *Controller) refreshLoadBalancer(name, dnsName, hostedZoneID string) {
+	c.loadBalancersMutex.Lock()
+	cached, ok := c.loadBalancers[name]
+	switch {
+	case !ok || dnsName == "" && cached.missing:
+		c.loadBalancersMutex.Unlock()
+		return
+	case dnsName == "":
+		c.loadBalancers[name] = loadBalancerAttributes{dnsName: cached.dnsName, hostedZoneID: cached.hostedZoneID, missing: true}
+	case dnsName != cached.dnsName || hostedZoneID != cached.hostedZoneID || cached.missing:
+		c.loadBalancers[name] = loadBalancerAttributes{dnsName: dnsName, hostedZoneID: hostedZoneID}
+	default:
+		c.loadBalancersMutex.Unlock()
+		return
+	}
+	c.loadBalancersMutex.Unlock()
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	hosts := c.loadBalancerHosts(name)
+	if dnsName == "" {
+		metrics.LoadBalancerChanges.WithLabelValues("deleted").Inc()
+		level.Warn(c.logger).Log("msg", "Load balancer not found anymore, its record sets are kept until it is recreated", "loadBalancer", name, "dnsName", cached.dnsName)
+		for _, host := range hosts {
+			c.recorder.Eventf(c.owner(host).source, corev1.EventTypeWarning, "LoadBalancerNotFound", "Load balancer %s of host %s was not found, the record set is kept", name, host)
+			c.setHostStatus(c.owner(host).source, host, func(status *hostStatus) {
+				status.LoadBalancer = "not found"
+			})
+		}
+		return
+	}
+
+	metrics.LoadBalancerChanges.WithLabelValues("changed").Inc()
+	level.Info(c.logger).Log("msg", "Load balancer changed, pointing its hosts to it again", "loadBalancer", name, "from", cached.dnsName, "to", dnsName, "hostedZoneID", hostedZoneID)
+	for _, host := range hosts {
+		c.repointHost(host, name)
+		if cached.missing {
+			c.setHostStatus(c.owner(host).source, host, func(status *hostStatus) {
+				status.LoadBalancer = ""
+			})
+		}
+	}
+}
+
+// return the sorted hosts whose owner points to the load balancer with the given name
+func (c *Controller) loadBalancerHosts(name string) []string {
+	var hosts []string
+	for _, host := range c.sortedHosts() {
+		if c.owner(host).target.name == name {
+			hosts = append(hosts, host)
+		}
+	}
+	return hosts
+}
+
+// point the record set of the host to the current attributes of the load balancer of its owner
+func (c *Controller) repointHost(host, name string) {
+	if c.reconcileHost(host) {
+		aliasName, _ := c.getLoadBalancerAttributes(c.owner(host).target)
+		c.recorder.Eventf(c.owner(host).source, corev1.EventTypeNormal, "LoadBalancerChanged", "Load balancer %s changed, host %s points to %s now", name, host, aliasName)
+	}
+}
+
+// return the cached attributes of the load balancer with the given name, which are resolved once if they are not cached yet.
+// A load balancer which is not found is cached as missing, so the next refresh looks it up again.
+func (c *Controller) cachedLoadBalancerAttributes(name string) (string, string) {
+	c.loadBalancersMutex.Lock()
+	cached, ok := c.loadBalancers[name]
+	c.loadBalancersMutex.Unlock()
+	if ok {
+		if cached.missing {
+			return "", ""
+		}
+		return cached.dnsName, cached.hostedZoneID
+	}
+
+	dnsName, hostedZoneID := c.resolveLoadBalancer(name)
+	c.loadBalancersMutex.Lock()
+	c.loadBalancers[name] = loadBalancerAttributes{dnsName: dnsName, hostedZoneID: hostedZoneID, missing: dnsName == ""}
+	c.loadBalancersMutex.Unlock()
+	return dnsName, hostedZoneID
+}
+
+// report that the DNS name of the load balancer of the host is unknown, no record set is written for it
+func (c *Controller) reportLoadBalancerNotFound(host string, target loadBalancer, sourceObj recordSource) {
+	level.Error(c.logger).Log("msg", "Load balancer not found, no Route53 record set is written", "target", target.String(), "hostName", host, "resource", sourceKey(sourceObj))
+	c.recorder.Eventf(sourceObj, corev1.EventTypeWarning, "LoadBalancerNotFound", "Load balancer %s of host %s was not found, no Route53 record set is written", target, host)
+	c.setHostStatus(sourceObj, host, func(status *hostStatus) {
+		status.LoadBalancer = "not found"
+	})
+}
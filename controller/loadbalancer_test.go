@@ -0,0 +1,146 @@
+package controller
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/service/route53"
+	"github.com/dbsystel/AmazonRoute53-ingress-controller/aws"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestRefreshLoadBalancer(t *testing.T) {
+	const (
+		old      = "old-123.eu-central-1.elb.amazonaws.com"
+		recycled = "new-456.eu-central-1.elb.amazonaws.com"
+	)
+	tests := []struct {
+		name        string
+		cached      loadBalancerAttributes
+		status      string
+		lookedUp    string
+		want        loadBalancerAttributes
+		wantStatus  string
+		wantTargets []string
+	}{
+		{
+			name:        "unchanged",
+			cached:      loadBalancerAttributes{dnsName: old},
+			lookedUp:    old,
+			want:        loadBalancerAttributes{dnsName: old},
+			wantTargets: []string{old + "."},
+		},
+		{
+			name:        "deletion is reported and the record set is kept",
+			cached:      loadBalancerAttributes{dnsName: old},
+			want:        loadBalancerAttributes{dnsName: old, missing: true},
+			wantStatus:  "not found",
+			wantTargets: []string{old + "."},
+		},
+		{
+			name:        "recreation clears the report and repoints the host",
+			cached:      loadBalancerAttributes{dnsName: old, missing: true},
+			status:      "not found",
+			lookedUp:    recycled,
+			want:        loadBalancerAttributes{dnsName: recycled},
+			wantTargets: []string{recycled + "."},
+		},
+		{
+			name:        "change repoints the host",
+			cached:      loadBalancerAttributes{dnsName: old},
+			lookedUp:    recycled,
+			want:        loadBalancerAttributes{dnsName: recycled},
+			wantTargets: []string{recycled + "."},
+		},
+	}
+	for _, test := range tests {
+		c, zones, cleanup := newZoneFileController(t, "")
+		c.EnableLoadBalancerCache()
+		c.loadBalancers["lb"] = test.cached
+		writeRecordSets(t, zones, aws.NewResourceRecordSet("app.example.com", route53.RRTypeCname, 300, []string{old}))
+		service := newStatusSource(t, c, "app")
+		c.addReference("app.example.com", service, loadBalancer{name: "lb"})
+		if test.status != "" {
+			c.setHostStatus(service, "app.example.com", func(status *hostStatus) {
+				status.LoadBalancer = test.status
+			})
+		}
+
+		c.refreshLoadBalancer("lb", test.lookedUp, "")
+
+		if got := c.loadBalancers["lb"]; got != test.want {
+			t.Errorf("%s: got cached %+v, want %+v", test.name, got, test.want)
+		}
+		var status string
+		if current := hostStatusOf(t, c, service, "app.example.com"); current != nil {
+			status = current.LoadBalancer
+		}
+		if status != test.wantStatus {
+			t.Errorf("%s: got load balancer status %q, want %q", test.name, status, test.wantStatus)
+		}
+		live, err := c.liveRecordSet("app.example.com", "example.com", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		var targets []string
+		for _, resourceRecord := range live.ResourceRecords {
+			targets = append(targets, *resourceRecord.Value)
+		}
+		if !reflect.DeepEqual(targets, test.wantTargets) {
+			t.Errorf("%s: got targets %v, want %v", test.name, targets, test.wantTargets)
+		}
+		cleanup()
+	}
+}
+
+func TestLoadBalancerNotFound(t *testing.T) {
+	const found = "app-123.eu-central-1.elb.amazonaws.com"
+	tests := []struct {
+		name   string
+		cached bool
+		target string
+		status string
+	}{
+		{"unknown static load balancer is skipped", false, "", "not found"},
+		{"missing cached load balancer is skipped", true, "", "not found"},
+		{"missing cached load balancer is published once the refresh finds it", true, found + ".", ""},
+	}
+	for _, test := range tests {
+		c, _, cleanup := newZoneFileController(t, "")
+		if test.cached {
+			c.EnableLoadBalancerCache()
+			c.loadBalancers["lb"] = loadBalancerAttributes{missing: true}
+		} else {
+			c.UseStaticLoadBalancers(map[string]string{})
+		}
+		ingressObj := newIngress(t, c, "app", "lb", "app.example.com")
+		c.Create(ingressObj)
+		if test.target != "" {
+			c.refreshLoadBalancer("lb", found, "")
+		}
+
+		live, err := c.liveRecordSet("app.example.com", "example.com", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		var target string
+		if live != nil {
+			target = *live.ResourceRecords[0].Value
+		}
+		if target != test.target {
+			t.Errorf("%s: got target %q, want %q", test.name, target, test.target)
+		}
+		writeStatuses(t, c)
+		status, err := c.kclient.NetworkingV1beta1().Ingresses("default").Get("app", metav1.GetOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		statuses := map[string]*hostStatus{}
+		json.Unmarshal([]byte(status.Annotations[statusAnnotation]), &statuses)
+		if got := statuses["app.example.com"]; got == nil || got.LoadBalancer != test.status {
+			t.Errorf("%s: got status %+v, want load balancer %q", test.name, got, test.status)
+		}
+		cleanup()
+	}
+}
@@ -0,0 +1,28 @@
+package controller
+
+import (
+	corelisters "k8s.io/client-go/listers/core/v1"
+)
+
+// namespace annotation with the comma separated domain patterns granted to the namespace.
+// Grants are glob patterns, a grant starting with "." matches every host below that domain.
+const domainsAnnotation = "ingress.net/route53-domains"
+
+// UseNamespaceLister sets the cache of the namespaces whose grants are checked by the namespace policy,
+// it is required before any event is handled if the namespace policy is enabled
+func (c *Controller) UseNamespaceLister(namespaces corelisters.NamespaceLister) {
+	c.namespaces = namespaces
+}
+
+// return the grants of the value of a domains annotation, which are compiled once per value like the allowlist
+func (c *Controller) namespaceGrants(value string) []glob {
+	if grants, ok := c.grants.Load(value); ok {
+		return grants.([]glob)
+	}
+	var grants []glob
+	for _, pattern := range splitList(value) {
+		grants = append(grants, compileGlob(pattern))
+	}
+	c.grants.Store(value, grants)
+	return grants
+}
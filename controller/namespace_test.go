@@ -0,0 +1,34 @@
+package controller
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNamespaceGrants(t *testing.T) {
+	c := &Controller{}
+	tests := []struct {
+		value    string
+		patterns []string
+	}{
+		{".a.example.com, app.example.com", []string{".a.example.com", "app.example.com"}},
+		{"*.b.example.com,,", []string{"*.b.example.com"}},
+		{"", nil},
+	}
+	for _, test := range tests {
+		grants := c.namespaceGrants(test.value)
+		var patterns []string
+		for _, grant := range grants {
+			patterns = append(patterns, grant.pattern)
+		}
+		if !reflect.DeepEqual(patterns, test.patterns) {
+			t.Errorf("%q: got %v, want %v", test.value, patterns, test.patterns)
+		}
+		// the grants of a value are compiled only once
+		for i, grant := range c.namespaceGrants(test.value) {
+			if grant.re != grants[i].re {
+				t.Errorf("%q: grant %s was compiled again", test.value, grant.pattern)
+			}
+		}
+	}
+}
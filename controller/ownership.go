@@ -0,0 +1,93 @@
+package controller
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/service/route53"
+	"github.com/dbsystel/AmazonRoute53-ingress-controller/aws"
+	"github.com/go-kit/kit/log/level"
+)
+
+const (
+	// prefix of the TXT record marking the owner of a host
+	ownershipPrefix = "_r53-ingress-owner."
+	// heritage of ownership records written by this controller
+	ownershipHeritage = "amazonroute53-ingress-controller"
+	// label replacing the "*" of a wildcard host in the name of its ownership record
+	ownershipWildcard = "_wildcard"
+)
+
+// return the name of the ownership record of the host.
+// The ownership record of a wildcard host must not be a wildcard itself, so its "*" label is replaced.
+func ownershipName(host string) string {
+	if strings.HasPrefix(host, "*.") {
+		host = ownershipWildcard + strings.TrimPrefix(host, "*")
+	}
+	return ownershipPrefix + host
+}
+
+// check that the name of the ownership record of the host is no longer than a DNS name may be
+func validateOwnershipName(host string) error {
+	if len(ownershipName(host)) > maxHostLength {
+		return fmt.Errorf("name of its ownership record is longer than %d characters", maxHostLength)
+	}
+	return nil
+}
+
+// return the value of the ownership record for the host referenced by the resource
+func (c *Controller) ownershipValue(sourceObj recordSource) string {
+	return "heritage=" + ownershipHeritage + ",owner=" + c.config.OwnerID + ",resource=" + sourceKey(sourceObj)
+}
+
+// parse the value of an ownership record into its key value pairs, returns nil if it was not written by this controller
+func parseOwnership(resourceRecordSet *route53.ResourceRecordSet) map[string]string {
+	for _, resourceRecord := range resourceRecordSet.ResourceRecords {
+		value, err := strconv.Unquote(*resourceRecord.Value)
+		if err != nil {
+			value = *resourceRecord.Value
+		}
+		ownership := make(map[string]string)
+		for _, pair := range strings.Split(value, ",") {
+			keyValue := strings.SplitN(pair, "=", 2)
+			if len(keyValue) == 2 {
+				ownership[keyValue[0]] = keyValue[1]
+			}
+		}
+		if ownership["heritage"] == ownershipHeritage {
+			return ownership
+		}
+	}
+	return nil
+}
+
+// return the upsert of the ownership record of the host referenced by the resource, or nil without owner ID
+func (c *Controller) ownershipUpsert(host string, sourceObj recordSource) *route53.Change {
+	if c.config.OwnerID == "" {
+		return nil
+	}
+	return aws.NewTXTChange(route53.ChangeActionUpsert, ownershipName(host), c.ownershipValue(sourceObj), c.config.TTL)
+}
+
+// return the deletion of the ownership record of the host, or nil if there is no ownership record of this owner
+func (c *Controller) ownershipDeletion(host, hostedZoneID, roleARN string) *route53.Change {
+	if c.config.OwnerID == "" {
+		return nil
+	}
+
+	// a DELETE has to match the existing record exactly and must only remove our own ownership record
+	existing, err := c.liveRecordSetOfType(ownershipName(host), route53.RRTypeTxt, hostedZoneID, roleARN)
+	if err != nil {
+		c.handleError(err)
+		return nil
+	}
+	if existing == nil {
+		return nil
+	}
+	if ownership := parseOwnership(existing); ownership == nil || ownership["owner"] != c.config.OwnerID {
+		level.Info(c.logger).Log("msg", "Ownership record belongs to another owner, keeping it", "hostName", host, "ownerID", c.config.OwnerID)
+		return nil
+	}
+	return aws.NewRecordSetChange(route53.ChangeActionDelete, existing)
+}
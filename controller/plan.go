@@ -0,0 +1,101 @@
+package controller
+
+import (
+	"sort"
+	"strconv"
+
+	"github.com/aws/aws-sdk-go/service/route53"
+	"github.com/dbsystel/AmazonRoute53-ingress-controller/aws"
+	"github.com/go-kit/kit/log"
+	"k8s.io/api/networking/v1beta1"
+	"k8s.io/client-go/tools/record"
+)
+
+// PlannedRecord is a record set which the controller would write for a host of an ingress resource
+type PlannedRecord struct {
+	Resource string `json:"resource"`
+	Host     string `json:"host"`
+	Type     string `json:"type,omitempty"`
+	TTL      int64  `json:"ttl,omitempty"`
+	Value    string `json:"value,omitempty"`
+	// false if the host is not in the allowlist or invalid, no record set would be written then
+	Allowed bool   `json:"allowed"`
+	Reason  string `json:"reason"`
+}
+
+// NewPlanner creates a controller which only plans record sets, it neither contacts the cluster nor the DNS provider.
+// Load balancer names are resolved by the given DNS names only.
+func NewPlanner(logger log.Logger, config Config, tlsHosts bool, staticLoadBalancers map[string]string) *Controller {
+	controller := &Controller{}
+	controller.logger = logger
+	// events are discarded
+	controller.recorder = &record.FakeRecorder{}
+	controller.config = config
+	controller.tlsHosts = tlsHosts
+	controller.UseStaticLoadBalancers(staticLoadBalancers)
+	return controller
+}
+
+// Plan returns the record sets which would be written for the ingress resource, including its ownership records.
+// The value of a record set is the DNS name of the static load balancer, a host whose load balancer is not defined
+// is not allowed. Hosts at the apex of a hosted zone are planned with the configured record type.
+func (c *Controller) Plan(ingressObj *v1beta1.Ingress) []PlannedRecord {
+	if isR53, _ := strconv.ParseBool(ingressObj.Annotations["ingress.net/route53"]); !isR53 {
+		return nil
+	}
+
+	var planned []PlannedRecord
+	hosts, invalid := normalizeHosts(c.rawHosts(ingressObj))
+	var invalidHosts []string
+	for host := range invalid {
+		invalidHosts = append(invalidHosts, host)
+	}
+	sort.Strings(invalidHosts)
+	for _, host := range invalidHosts {
+		planned = append(planned, PlannedRecord{Resource: sourceKey(ingressObj), Host: host, Reason: invalid[host].Error()})
+	}
+	target := ingressTarget(ingressObj)
+	for _, host := range hosts {
+		decision := c.decideHost(host, ingressObj)
+		reason := decision.reason(ingressObj.Namespace)
+		if !decision.allowed || !decision.delegated {
+			planned = append(planned, PlannedRecord{Resource: sourceKey(ingressObj), Host: host, Reason: reason})
+			continue
+		}
+		if target.String() == "" {
+			planned = append(planned, PlannedRecord{Resource: sourceKey(ingressObj), Host: host, Reason: "annotation ingress.net/load-balancer-name is missing"})
+			continue
+		}
+		aliasName, aliasHostedZoneID := c.getLoadBalancerAttributes(target)
+		if aliasName == "" {
+			planned = append(planned, PlannedRecord{Resource: sourceKey(ingressObj), Host: host, Reason: "load balancer " + target.String() + " is not defined by --static-load-balancer"})
+			continue
+		}
+
+		for _, change := range c.upsertChanges(host, aliasName, aliasHostedZoneID, c.config.DNSType, ingressObj) {
+			planned = append(planned, plannedRecord(sourceKey(ingressObj), reason, change.ResourceRecordSet))
+		}
+	}
+	return planned
+}
+
+// return the planned record of the record set
+func plannedRecord(resource, reason string, resourceRecordSet *route53.ResourceRecordSet) PlannedRecord {
+	planned := PlannedRecord{
+		Resource: resource,
+		Host:     aws.NormalizeName(*resourceRecordSet.Name),
+		Type:     *resourceRecordSet.Type,
+		Allowed:  true,
+		Reason:   reason,
+	}
+	if resourceRecordSet.AliasTarget != nil {
+		planned.Type = "ALIAS " + planned.Type
+		planned.Value = *resourceRecordSet.AliasTarget.DNSName
+		return planned
+	}
+	planned.TTL = *resourceRecordSet.TTL
+	for _, resourceRecord := range resourceRecordSet.ResourceRecords {
+		planned.Value = *resourceRecord.Value
+	}
+	return planned
+}
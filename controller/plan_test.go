@@ -0,0 +1,85 @@
+package controller
+
+import (
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	"k8s.io/api/networking/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newPlannedIngress(loadBalancerName string, hosts ...string) *v1beta1.Ingress {
+	ingressObj := &v1beta1.Ingress{ObjectMeta: metav1.ObjectMeta{
+		Namespace:   "default",
+		Name:        "app",
+		Annotations: map[string]string{"ingress.net/route53": "true"},
+	}}
+	if loadBalancerName != "" {
+		ingressObj.Annotations["ingress.net/load-balancer-name"] = loadBalancerName
+	}
+	for _, host := range hosts {
+		ingressObj.Spec.Rules = append(ingressObj.Spec.Rules, v1beta1.IngressRule{Host: host})
+	}
+	return ingressObj
+}
+
+func TestPlan(t *testing.T) {
+	allowlist, err := NewAllowlist(nil, []string{".example.com"}, nil, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	planner := NewPlanner(log.NewNopLogger(), Config{Allowlist: allowlist, DNSType: "cname", TTL: 60, OwnerID: "ci"}, false, map[string]string{"app": "app-123.eu-central-1.elb.amazonaws.com"})
+
+	tests := []struct {
+		name    string
+		ingress *v1beta1.Ingress
+		planned []PlannedRecord
+	}{
+		{
+			name:    "allowed host",
+			ingress: newPlannedIngress("app", "App.example.com"),
+			planned: []PlannedRecord{
+				{Resource: "ingress/default/app", Host: "app.example.com", Type: "CNAME", TTL: 60, Value: "app-123.eu-central-1.elb.amazonaws.com", Allowed: true, Reason: "allowed by rule suffix:.example.com"},
+				{Resource: "ingress/default/app", Host: "_r53-ingress-owner.app.example.com", Type: "TXT", TTL: 60, Value: `"heritage=amazonroute53-ingress-controller,owner=ci,resource=ingress/default/app"`, Allowed: true, Reason: "allowed by rule suffix:.example.com"},
+			},
+		},
+		{
+			name:    "host not in allowlist",
+			ingress: newPlannedIngress("app", "app.example.org"),
+			planned: []PlannedRecord{{Resource: "ingress/default/app", Host: "app.example.org", Reason: "not allowed by rule none"}},
+		},
+		{
+			name:    "invalid host",
+			ingress: newPlannedIngress("app", "app_1.example.com"),
+			planned: []PlannedRecord{{Resource: "ingress/default/app", Host: "app_1.example.com", Reason: invalidHostReason("app_1.example.com")}},
+		},
+		{
+			name:    "missing load balancer annotation",
+			ingress: newPlannedIngress("", "app.example.com"),
+			planned: []PlannedRecord{{Resource: "ingress/default/app", Host: "app.example.com", Reason: "annotation ingress.net/load-balancer-name is missing"}},
+		},
+		{
+			name:    "undefined load balancer",
+			ingress: newPlannedIngress("other", "app.example.com"),
+			planned: []PlannedRecord{{Resource: "ingress/default/app", Host: "app.example.com", Reason: "load balancer other is not defined by --static-load-balancer"}},
+		},
+	}
+	for _, test := range tests {
+		planned := planner.Plan(test.ingress)
+		if len(planned) != len(test.planned) {
+			t.Errorf("%s: got %+v, want %+v", test.name, planned, test.planned)
+			continue
+		}
+		for i := range planned {
+			if planned[i] != test.planned[i] {
+				t.Errorf("%s: got %+v, want %+v", test.name, planned[i], test.planned[i])
+			}
+		}
+	}
+}
+
+// return the reason of the invalid host as planned
+func invalidHostReason(host string) string {
+	_, invalid := normalizeHosts([]string{host})
+	return invalid[host].Error()
+}
@@ -0,0 +1,57 @@
+package controller
+
+import (
+	"github.com/aws/aws-sdk-go/service/route53"
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	// PolicySync creates, updates and deletes record sets
+	PolicySync = "sync"
+	// PolicyUpsertOnly creates and updates record sets, but never deletes them
+	PolicyUpsertOnly = "upsert-only"
+	// PolicyCreateOnly never updates or deletes existing record sets which are not owned by the controller
+	PolicyCreateOnly = "create-only"
+)
+
+// Policies lists all supported policies
+var Policies = []string{PolicySync, PolicyUpsertOnly, PolicyCreateOnly}
+
+// check if the policy allows the change of the record set, returns the reason if not
+func (c *Controller) allowedByPolicy(state, host string, live *route53.ResourceRecordSet, hostedZoneID, roleARN string, sourceObj recordSource) (bool, string) {
+	switch c.policy {
+	case PolicyUpsertOnly:
+		if state == route53.ChangeActionDelete {
+			return false, "record sets are never deleted with policy " + PolicyUpsertOnly
+		}
+	case PolicyCreateOnly:
+		if live == nil {
+			return state != route53.ChangeActionDelete, "record set does not exist"
+		}
+		if !c.isOwned(host, hostedZoneID, roleARN) {
+			reason := "existing record set is not owned by owner ID " + c.config.OwnerID
+			if state != route53.ChangeActionDelete {
+				c.recorder.Eventf(sourceObj, corev1.EventTypeWarning, "RecordNotOwned", "Host %s is not changed with policy %s: %s", host, PolicyCreateOnly, reason)
+			}
+			return false, reason
+		}
+	}
+	return true, ""
+}
+
+// check if the ownership record of the host carries our owner ID
+func (c *Controller) isOwned(host, hostedZoneID, roleARN string) bool {
+	if c.config.OwnerID == "" {
+		return false
+	}
+	existing, err := c.liveRecordSetOfType(ownershipName(host), route53.RRTypeTxt, hostedZoneID, roleARN)
+	if err != nil {
+		c.handleError(err)
+		return false
+	}
+	if existing == nil {
+		return false
+	}
+	ownership := parseOwnership(existing)
+	return ownership != nil && ownership["owner"] == c.config.OwnerID
+}
@@ -0,0 +1,60 @@
+package controller
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/service/route53"
+	"github.com/dbsystel/AmazonRoute53-ingress-controller/aws"
+)
+
+func TestPolicies(t *testing.T) {
+	const (
+		a      = "a-123.eu-central-1.elb.amazonaws.com"
+		b      = "b-456.eu-central-1.elb.amazonaws.com"
+		manual = "manual.example.net"
+	)
+	created := ingressEvent{"create", "app", "a", []string{"app.example.com"}}
+	repointed := ingressEvent{"update", "app", "b", []string{"app.example.com"}}
+	deleted := ingressEvent{"delete", "app", "b", []string{"app.example.com"}}
+	tests := []struct {
+		name    string
+		policy  string
+		ownerID string
+		// target of an existing record set of app.example.com without ownership record
+		existing string
+		events   []ingressEvent
+		want     map[string]string
+	}{
+		{"sync deletes", PolicySync, "", "", []ingressEvent{created, deleted}, map[string]string{}},
+		{"sync overwrites", PolicySync, "", manual, []ingressEvent{created}, map[string]string{"app.example.com/CNAME": a}},
+		{"upsert-only updates", PolicyUpsertOnly, "", "", []ingressEvent{created, repointed}, map[string]string{"app.example.com/CNAME": b}},
+		{"upsert-only never deletes", PolicyUpsertOnly, "", "", []ingressEvent{created, repointed, deleted}, map[string]string{"app.example.com/CNAME": b}},
+		{"upsert-only overwrites", PolicyUpsertOnly, "", manual, []ingressEvent{created}, map[string]string{"app.example.com/CNAME": a}},
+		{"create-only creates", PolicyCreateOnly, "ci", "", []ingressEvent{created}, map[string]string{"app.example.com/CNAME": a}},
+		{"create-only updates and deletes owned record sets", PolicyCreateOnly, "ci", "", []ingressEvent{created, repointed, deleted}, map[string]string{}},
+		{"create-only keeps existing record sets", PolicyCreateOnly, "ci", manual, []ingressEvent{created, repointed, deleted}, map[string]string{"app.example.com/CNAME": manual}},
+		{"create-only without owner ID owns nothing", PolicyCreateOnly, "", "", []ingressEvent{created, repointed, deleted}, map[string]string{"app.example.com/CNAME": a}},
+	}
+	for _, test := range tests {
+		c, zones, cleanup := newZoneFileController(t, test.ownerID)
+		c.policy = test.policy
+		c.UseStaticLoadBalancers(map[string]string{"a": a, "b": b})
+		if test.existing != "" {
+			writeRecordSets(t, zones, aws.NewResourceRecordSet("app.example.com", route53.RRTypeCname, 300, []string{test.existing}))
+		}
+		handleIngressEvents(t, c, test.events)
+
+		// ownership records are not compared
+		got := zoneTargets(t, zones)
+		for key := range got {
+			if key == ownershipName("app.example.com")+"/"+route53.RRTypeTxt {
+				delete(got, key)
+			}
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: got record sets %v, want %v", test.name, got, test.want)
+		}
+		cleanup()
+	}
+}
@@ -0,0 +1,126 @@
+package controller
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/route53"
+	"github.com/dbsystel/AmazonRoute53-ingress-controller/metrics"
+	"github.com/go-kit/kit/log/level"
+	"golang.org/x/time/rate"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/util/workqueue"
+)
+
+const (
+	// ID of the changes which are not submitted in dry run mode
+	dryRunChangeID             = "dry-run"
+	minPropagationPollInterval = 2 * time.Second
+	maxPropagationPollInterval = 30 * time.Second
+	// status requests per second of all pending changes, Amazon Route53 allows five requests per second per account
+	propagationPollRate  = 2
+	propagationPollBurst = 5
+)
+
+// pendingChange is a submitted change which is not INSYNC yet
+type pendingChange struct {
+	roleARN   string
+	state     string
+	host      string
+	sourceObj recordSource
+	submitted time.Time
+}
+
+// create the queue of the pending changes, each change is polled with backoff and all together with a limited rate
+func newChangeQueue() workqueue.RateLimitingInterface {
+	return workqueue.NewNamedRateLimitingQueue(workqueue.NewMaxOfRateLimiter(
+		workqueue.NewItemExponentialFailureRateLimiter(minPropagationPollInterval, maxPropagationPollInterval),
+		&workqueue.BucketRateLimiter{Limiter: rate.NewLimiter(rate.Limit(propagationPollRate), propagationPollBurst)},
+	), "changes")
+}
+
+// RunPropagationPoller polls the status of the submitted changes until stopCh is closed
+func (c *Controller) RunPropagationPoller(stopCh <-chan struct{}, wg *sync.WaitGroup) {
+	defer wg.Done()
+
+	go func() {
+		<-stopCh
+		c.changes.ShutDown()
+	}()
+	for c.pollChange() {
+	}
+}
+
+// track a submitted Amazon Route53 change until it is propagated to all authoritative name servers
+func (c *Controller) trackChange(changeID, roleARN, state, host string, sourceObj recordSource) {
+	if changeID == dryRunChangeID {
+		return
+	}
+	c.pendingMutex.Lock()
+	c.pendingChanges[changeID] = &pendingChange{roleARN: roleARN, state: state, host: host, sourceObj: sourceObj, submitted: time.Now()}
+	metrics.PendingChanges.Set(float64(len(c.pendingChanges)))
+	c.pendingMutex.Unlock()
+
+	if state != "DELETE" {
+		c.setHostStatus(sourceObj, host, func(status *hostStatus) {
+			status.ChangeID = changeID
+			status.Change = route53.ChangeStatusPending
+			status.PropagationSeconds = nil
+			status.LoadBalancer = ""
+		})
+	}
+
+	c.changes.AddRateLimited(changeID)
+}
+
+// poll the status of the next due change, which is queued again with backoff until it is INSYNC or
+// the propagation timeout is exceeded. Returns false if the queue is shut down.
+func (c *Controller) pollChange() bool {
+	item, shutdown := c.changes.Get()
+	if shutdown {
+		return false
+	}
+	defer c.changes.Done(item)
+
+	changeID := item.(string)
+	c.pendingMutex.Lock()
+	change, ok := c.pendingChanges[changeID]
+	c.pendingMutex.Unlock()
+	if !ok {
+		c.changes.Forget(item)
+		return true
+	}
+
+	status, err := c.provider.ChangeStatus(changeID, change.roleARN)
+	if err != nil {
+		c.handleError(err)
+	}
+	switch {
+	case err == nil && status == route53.ChangeStatusInsync:
+		propagation := time.Since(change.submitted).Seconds()
+		metrics.PropagationDuration.WithLabelValues(change.state).Observe(propagation)
+		level.Info(c.logger).Log("msg", "Route53 change is in sync", "changeID", changeID, "propagationSeconds", propagation, "hostName", change.host, "resource", sourceKey(change.sourceObj))
+		if change.state != "DELETE" {
+			c.setHostStatus(change.sourceObj, change.host, func(status *hostStatus) {
+				if status.ChangeID == changeID {
+					status.Change = route53.ChangeStatusInsync
+					status.PropagationSeconds = &propagation
+				}
+			})
+		}
+	case time.Since(change.submitted) > c.propagationTimeout:
+		level.Warn(c.logger).Log("msg", "Route53 change is still pending, giving up polling it", "changeID", changeID, "timeout", c.propagationTimeout, "hostName", change.host, "resource", sourceKey(change.sourceObj))
+		c.recorder.Event(change.sourceObj, corev1.EventTypeWarning, "PropagationTimeout", fmt.Sprintf("Route53 change %s for host %s is still pending after %s, its status is not polled anymore", changeID, change.host, c.propagationTimeout))
+	default:
+		c.changes.AddRateLimited(item)
+		return true
+	}
+
+	c.changes.Forget(item)
+	c.pendingMutex.Lock()
+	delete(c.pendingChanges, changeID)
+	metrics.PendingChanges.Set(float64(len(c.pendingChanges)))
+	c.pendingMutex.Unlock()
+	return true
+}
@@ -0,0 +1,96 @@
+package controller
+
+import (
+	"sort"
+
+	"github.com/dbsystel/AmazonRoute53-ingress-controller/metrics"
+)
+
+// hostReference is a resource referencing a host
+type hostReference struct {
+	source recordSource
+	target loadBalancer
+	// true if the reference points to another load balancer than the owner of the host
+	conflicting bool
+	// DNS name the record set of the host was last pointed to for this reference, empty if it never owned the host
+	published string
+}
+
+// add or replace the reference of the resource to the host
+func (c *Controller) addReference(host string, sourceObj recordSource, target loadBalancer) *hostReference {
+	if c.hostReferences[host] == nil {
+		c.hostReferences[host] = make(map[string]*hostReference)
+	}
+	reference := &hostReference{source: sourceObj, target: target}
+	if previous, ok := c.hostReferences[host][sourceKey(sourceObj)]; ok {
+		reference.conflicting = previous.conflicting
+		reference.published = previous.published
+	}
+	c.hostReferences[host][sourceKey(sourceObj)] = reference
+	c.updateHostMetrics()
+	return reference
+}
+
+// remove the reference of the resource to the host and return the number of remaining references
+func (c *Controller) removeReference(host string, sourceObj recordSource) int {
+	delete(c.hostReferences[host], sourceKey(sourceObj))
+	remaining := len(c.hostReferences[host])
+	if remaining == 0 {
+		delete(c.hostReferences, host)
+	}
+	c.updateHostMetrics()
+	return remaining
+}
+
+// return the owner of the host, which is the oldest resource referencing it
+func (c *Controller) owner(host string) *hostReference {
+	var owner *hostReference
+	for key, reference := range c.hostReferences[host] {
+		if owner == nil {
+			owner = reference
+			continue
+		}
+		created, ownerCreated := reference.source.GetCreationTimestamp(), owner.source.GetCreationTimestamp()
+		if created.Before(&ownerCreated) || created.Equal(&ownerCreated) && key < sourceKey(owner.source) {
+			owner = reference
+		}
+	}
+	return owner
+}
+
+// return all references of the host pointing to another load balancer than its owner
+func (c *Controller) conflicts(host string) []*hostReference {
+	owner := c.owner(host)
+	var conflicts []*hostReference
+	for _, reference := range c.hostReferences[host] {
+		if reference.target != owner.target {
+			conflicts = append(conflicts, reference)
+		}
+	}
+	return conflicts
+}
+
+// refresh gauges derived from the host references
+func (c *Controller) updateHostMetrics() {
+	references := 0
+	conflicts := 0
+	for host, hostReferences := range c.hostReferences {
+		references += len(hostReferences)
+		if len(c.conflicts(host)) > 0 {
+			conflicts++
+		}
+	}
+	metrics.HostsManaged.Set(float64(len(c.hostReferences)))
+	metrics.HostReferenceCounterSize.Set(float64(references))
+	metrics.ActiveConflicts.Set(float64(conflicts))
+}
+
+// return all referenced hosts, sorted by name
+func (c *Controller) sortedHosts() []string {
+	hosts := make([]string, 0, len(c.hostReferences))
+	for host := range c.hostReferences {
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts)
+	return hosts
+}
@@ -0,0 +1,131 @@
+package controller
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/go-kit/kit/log/level"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// service annotation with the comma separated hosts pointing to the load balancer of the service
+const serviceHostnameAnnotation = "ingress.net/route53-hostname"
+
+// ServiceController handles the events of services of type LoadBalancer
+type ServiceController struct {
+	*Controller
+}
+
+// Services returns the handler for events of services, which shares the host references with the ingress resources
+func (c *Controller) Services() *ServiceController {
+	return &ServiceController{Controller: c}
+}
+
+// Create will do something when a service is beeing created
+func (s *ServiceController) Create(obj interface{}) {
+	level.Debug(s.logger).Log("msg", "Called function: Create")
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	defer s.track("create")()
+	serviceObj := obj.(*corev1.Service)
+
+	if isR53Service(serviceObj) {
+		level.Info(s.logger).Log("msg", "Creation of a service detected", "serviceName", serviceObj.Name, "serviceNamespace", serviceObj.Namespace)
+
+		s.createServiceRecordSet(serviceObj)
+	}
+}
+
+// Update will do something when a service is beeing updated
+func (s *ServiceController) Update(oldobj interface{}, newobj interface{}) {
+	newServiceObj := newobj.(*corev1.Service)
+	oldServiceObj := oldobj.(*corev1.Service)
+
+	level.Debug(s.logger).Log("msg", "Called function: Update")
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	defer s.track("update")()
+	s.updateHeldDeletions(newServiceObj)
+
+	if noServiceDifference(oldServiceObj, newServiceObj) {
+		level.Debug(s.logger).Log("msg", "Skipping automatically updated service", "serviceName", newServiceObj.Name, "serviceNamespace", newServiceObj.Namespace)
+		return
+	}
+
+	isOldR53 := isR53Service(oldServiceObj)
+	isR53 := isR53Service(newServiceObj)
+
+	if isOldR53 {
+		var hosts []string
+		if isR53 {
+			// hosts still present in the new service are replaced by createServiceRecordSet
+			hosts = s.removedHosts(oldServiceObj, serviceHosts(oldServiceObj), serviceHosts(newServiceObj))
+		} else {
+			hosts = s.validHosts("delete", oldServiceObj, serviceHosts(oldServiceObj))
+		}
+		level.Info(s.logger).Log("msg", "Update of a service detected, the removed hosts will be deleted.", "serviceName", oldServiceObj.Name, "serviceNamespace", oldServiceObj.Namespace)
+
+		s.deleteRecordSet(oldServiceObj, hosts)
+	}
+
+	if isR53 {
+		level.Info(s.logger).Log("msg", "Update of a service detected, the new one will be created.", "serviceName", newServiceObj.Name, "serviceNamespace", newServiceObj.Namespace)
+
+		s.createServiceRecordSet(newServiceObj)
+	}
+}
+
+// Delete will do something when a service is beeing deleted
+func (s *ServiceController) Delete(obj interface{}) {
+	level.Debug(s.logger).Log("msg", "Called function: Delete")
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	defer s.track("delete")()
+	serviceObj := obj.(*corev1.Service)
+
+	if isR53Service(serviceObj) {
+		level.Info(s.logger).Log("msg", "Deletion of a service detected", "serviceName", serviceObj.Name, "serviceNamespace", serviceObj.Namespace)
+
+		s.deleteRecordSet(serviceObj, s.validHosts("delete", serviceObj, serviceHosts(serviceObj)))
+		s.forgetStatus(serviceObj)
+	}
+}
+
+// create the record sets of the service once its load balancer has been provisioned
+func (s *ServiceController) createServiceRecordSet(serviceObj *corev1.Service) {
+	target := serviceTarget(serviceObj)
+	if target.dnsName == "" {
+		level.Info(s.logger).Log("msg", "The load balancer of the service has no hostname yet. Skipping creation/updating!", "serviceName", serviceObj.Name, "serviceNamespace", serviceObj.Namespace)
+		return
+	}
+	s.createRecordSet(serviceObj, s.validHosts("create", serviceObj, serviceHosts(serviceObj)), target)
+}
+
+// check if the record sets of the service are managed
+func isR53Service(serviceObj *corev1.Service) bool {
+	isR53, _ := strconv.ParseBool(serviceObj.Annotations["ingress.net/route53"])
+	return isR53 && serviceObj.Spec.Type == corev1.ServiceTypeLoadBalancer
+}
+
+// return the hosts of the service given by its annotation
+func serviceHosts(serviceObj *corev1.Service) []string {
+	return splitList(serviceObj.Annotations[serviceHostnameAnnotation])
+}
+
+// return the load balancer of the service given by the hostname in its status
+func serviceTarget(serviceObj *corev1.Service) loadBalancer {
+	for _, ingress := range serviceObj.Status.LoadBalancer.Ingress {
+		if ingress.Hostname != "" {
+			return loadBalancer{dnsName: strings.ToLower(ingress.Hostname)}
+		}
+	}
+	return loadBalancer{}
+}
+
+// are the two services same regarding their record sets?
+func noServiceDifference(newServiceObj *corev1.Service, oldServiceObj *corev1.Service) bool {
+	return isR53Service(newServiceObj) == isR53Service(oldServiceObj) &&
+		newServiceObj.Annotations[serviceHostnameAnnotation] == oldServiceObj.Annotations[serviceHostnameAnnotation] &&
+		newServiceObj.Annotations[allowMassDeletionAnnotation] == oldServiceObj.Annotations[allowMassDeletionAnnotation] &&
+		serviceTarget(newServiceObj) == serviceTarget(oldServiceObj)
+}
@@ -0,0 +1,150 @@
+package controller
+
+import (
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// serviceEvent is an event of the service app, its load balancer hostname is pending if empty
+type serviceEvent struct {
+	action      string
+	serviceType corev1.ServiceType
+	hostname    string
+	hosts       string
+}
+
+// return the published service app with the hosts and the load balancer hostname in its status
+func newService(event serviceEvent) *corev1.Service {
+	serviceObj := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:   "default",
+			Name:        "app",
+			UID:         types.UID("app"),
+			Annotations: map[string]string{"ingress.net/route53": "true", serviceHostnameAnnotation: event.hosts},
+		},
+		Spec: corev1.ServiceSpec{Type: event.serviceType},
+	}
+	if event.hostname != "" {
+		serviceObj.Status.LoadBalancer.Ingress = []corev1.LoadBalancerIngress{{Hostname: event.hostname}}
+	}
+	return serviceObj
+}
+
+func TestServiceEvents(t *testing.T) {
+	const (
+		a = "a-123.elb.eu-central-1.amazonaws.com"
+		b = "b-456.elb.eu-central-1.amazonaws.com"
+	)
+	loadBalancer := corev1.ServiceTypeLoadBalancer
+	tests := []struct {
+		name   string
+		events []serviceEvent
+		want   map[string]string
+	}{
+		{
+			name:   "create",
+			events: []serviceEvent{{"create", loadBalancer, a, "app.example.com, www.example.com"}},
+			want:   map[string]string{"app.example.com/CNAME": a, "www.example.com/CNAME": a},
+		},
+		{
+			name:   "other service types are not published",
+			events: []serviceEvent{{"create", corev1.ServiceTypeClusterIP, a, "app.example.com"}},
+			want:   map[string]string{},
+		},
+		{
+			name:   "pending load balancer",
+			events: []serviceEvent{{"create", loadBalancer, "", "app.example.com"}},
+			want:   map[string]string{},
+		},
+		{
+			name: "published once the load balancer is provisioned",
+			events: []serviceEvent{
+				{"create", loadBalancer, "", "app.example.com"},
+				{"update", loadBalancer, a, "app.example.com"},
+			},
+			want: map[string]string{"app.example.com/CNAME": a},
+		},
+		{
+			name: "repointed to a new load balancer",
+			events: []serviceEvent{
+				{"create", loadBalancer, a, "app.example.com"},
+				{"update", loadBalancer, b, "app.example.com"},
+			},
+			want: map[string]string{"app.example.com/CNAME": b},
+		},
+		{
+			name: "changed hosts",
+			events: []serviceEvent{
+				{"create", loadBalancer, a, "app.example.com, old.example.com"},
+				{"update", loadBalancer, a, "app.example.com, new.example.com"},
+			},
+			want: map[string]string{"app.example.com/CNAME": a, "new.example.com/CNAME": a},
+		},
+		{
+			name: "changed service type",
+			events: []serviceEvent{
+				{"create", loadBalancer, a, "app.example.com"},
+				{"update", corev1.ServiceTypeClusterIP, a, "app.example.com"},
+			},
+			want: map[string]string{},
+		},
+		{
+			name: "delete",
+			events: []serviceEvent{
+				{"create", loadBalancer, a, "app.example.com"},
+				{"delete", loadBalancer, a, "app.example.com"},
+			},
+			want: map[string]string{},
+		},
+	}
+	for _, test := range tests {
+		c, zones, cleanup := newZoneFileController(t, "")
+		services := c.Services()
+		var previous *corev1.Service
+		for _, event := range test.events {
+			serviceObj := newService(event)
+			switch event.action {
+			case "create":
+				if _, err := c.kclient.CoreV1().Services("default").Create(serviceObj); err != nil {
+					t.Fatal(err)
+				}
+				services.Create(serviceObj)
+			case "update":
+				services.Update(previous, serviceObj)
+			case "delete":
+				services.Delete(serviceObj)
+			}
+			previous = serviceObj
+		}
+
+		if got := zoneTargets(t, zones); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: got record sets %v, want %v", test.name, got, test.want)
+		}
+		cleanup()
+	}
+}
+
+func TestServiceSharesHostWithIngress(t *testing.T) {
+	const a = "a-123.elb.eu-central-1.amazonaws.com"
+	c, zones, cleanup := newZoneFileController(t, "")
+	defer cleanup()
+	c.UseStaticLoadBalancers(map[string]string{"a": a})
+	ingressObj := newIngress(t, c, "app", "a", "app.example.com")
+	serviceObj := newService(serviceEvent{"create", corev1.ServiceTypeLoadBalancer, a, "app.example.com"})
+	c.Create(ingressObj)
+	c.Services().Create(serviceObj)
+
+	// the references of both resources are counted together
+	c.Delete(ingressObj)
+	if got, want := zoneTargets(t, zones), map[string]string{"app.example.com/CNAME": a}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got record sets %v after deleting the ingress resource, want %v", got, want)
+	}
+	c.Services().Delete(serviceObj)
+	if got := zoneTargets(t, zones); len(got) != 0 {
+		t.Errorf("got record sets %v after deleting the service, want none", got)
+	}
+}
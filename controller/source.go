@@ -0,0 +1,128 @@
+package controller
+
+import (
+	"strings"
+
+	"github.com/dbsystel/AmazonRoute53-ingress-controller/aws"
+	"github.com/go-kit/kit/log/level"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/api/networking/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// resources of the sources which are custom resources, by group and kind
+var dynamicResources = map[schema.GroupKind]schema.GroupVersionResource{
+	{Group: gatewayGroup, Kind: "Gateway"}:      GatewayResource,
+	{Group: gatewayGroup, Kind: "HTTPRoute"}:    HTTPRouteResource,
+	{Group: istioGroup, Kind: "Gateway"}:        IstioGatewayResource,
+	{Group: istioGroup, Kind: "VirtualService"}: VirtualServiceResource,
+	{Group: dnsRecordGroup, Kind: "DNSRecord"}:  DNSRecordResource,
+}
+
+// recordSource is a kubernetes resource whose hosts are published as record sets, e.g. an ingress resource or a service
+type recordSource interface {
+	runtime.Object
+	metav1.Object
+}
+
+// loadBalancer is the target of the record sets of a resource, given by its name or by its DNS name
+type loadBalancer struct {
+	name    string
+	dnsName string
+}
+
+// String returns the name of the load balancer, or its DNS name if the name is unknown
+func (l loadBalancer) String() string {
+	if l.name != "" {
+		return l.name
+	}
+	return l.dnsName
+}
+
+// return the kind of the resource as used in logs and ownership records
+func sourceKind(sourceObj recordSource) string {
+	switch obj := sourceObj.(type) {
+	case *v1beta1.Ingress:
+		return "ingress"
+	case *corev1.Service:
+		return "service"
+	case *unstructured.Unstructured:
+		// custom resources are qualified by their group, e.g. httproute.gateway.networking.k8s.io
+		return strings.ToLower(obj.GetKind()) + "." + obj.GroupVersionKind().Group
+	}
+	return "unknown"
+}
+
+// return the key of a resource within the host references, e.g. ingress/default/app
+func sourceKey(sourceObj recordSource) string {
+	return sourceKind(sourceObj) + "/" + sourceObj.GetNamespace() + "/" + sourceObj.GetName()
+}
+
+// return dnsName and hostedZoneNameID of the load balancer
+func (c *Controller) getLoadBalancerAttributes(target loadBalancer) (string, string) {
+	if target.name == "" {
+		if target.dnsName == "" {
+			return "", ""
+		}
+		if c.staticLoadBalancers != nil || !c.provider.AliasRecords() {
+			// the hosted zone of the load balancer is only needed for ALIAS records and never looked up with static load balancers
+			return target.dnsName, ""
+		}
+		return target.dnsName, c.loadBalancerHostedZoneID(target.dnsName)
+	}
+	if c.staticLoadBalancers != nil {
+		return c.staticLoadBalancerAttributes(target.name)
+	}
+	if c.loadBalancers != nil {
+		return c.cachedLoadBalancerAttributes(target.name)
+	}
+	return c.resolveLoadBalancer(target.name)
+}
+
+// return the canonical hosted zone ID of the AWS load balancer with the DNS name, which is looked up once per DNS name.
+// DNS names of other targets and all DNS names with static load balancers are never looked up.
+func (c *Controller) loadBalancerHostedZoneID(dnsName string) string {
+	if c.staticLoadBalancers != nil || !aws.IsLoadBalancerDNSName(dnsName) {
+		return ""
+	}
+	dnsName = strings.ToLower(aws.NormalizeName(dnsName))
+	if hostedZoneID, ok := c.hostedZoneIDs.Load(dnsName); ok {
+		return hostedZoneID.(string)
+	}
+	hostedZoneID := aws.GetLoadBalancerHostedZoneID(dnsName, c.logger)
+	if hostedZoneID != "" {
+		c.hostedZoneIDs.Store(dnsName, hostedZoneID)
+	}
+	return hostedZoneID
+}
+
+// return dnsName and hostedZoneNameID of the classic or application load balancer with the given name
+func (c *Controller) resolveLoadBalancer(name string) (string, string) {
+	dnsName, hostedZoneNameID := aws.GetELBAttributes(name, c.logger)
+	if dnsName == "" {
+		dnsName, hostedZoneNameID = aws.GetALBAttributes(name, c.logger)
+	}
+	return dnsName, hostedZoneNameID
+}
+
+// UseStaticLoadBalancers makes the controller resolve load balancer names by the given DNS names instead of the AWS API,
+// e.g. for the zonefile provider running without AWS credentials. Hosted zones of load balancers remain unknown.
+func (c *Controller) UseStaticLoadBalancers(dnsNames map[string]string) {
+	c.staticLoadBalancers = make(map[string]string, len(dnsNames))
+	for name, dnsName := range dnsNames {
+		c.staticLoadBalancers[name] = dnsName
+	}
+}
+
+// return the DNS name of the static load balancer with the given name, its hosted zone is unknown
+func (c *Controller) staticLoadBalancerAttributes(name string) (string, string) {
+	dnsName, ok := c.staticLoadBalancers[name]
+	if !ok {
+		level.Error(c.logger).Log("msg", "Load balancer is not defined by --static-load-balancer, its DNS name is unknown", "loadBalancer", name)
+		return "", ""
+	}
+	return dnsName, ""
+}
@@ -0,0 +1,223 @@
+package controller
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/route53"
+	"github.com/go-kit/kit/log/level"
+	"golang.org/x/time/rate"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/workqueue"
+)
+
+const (
+	// annotation holding the Amazon Route53 state of every host of a resource
+	statusAnnotation = "ingress.net/route53-status"
+	// writes of a resource are delayed at least by this interval, so the updates of its hosts are written together
+	minStatusWriteInterval = 100 * time.Millisecond
+	maxStatusWriteInterval = time.Minute
+	// status writes per second of all resources, well below the default rate limit of the kubernetes client
+	statusWriteRate  = 2
+	statusWriteBurst = 5
+)
+
+// hostStatus describes the Amazon Route53 state of a single host
+type hostStatus struct {
+	ChangeID           string   `json:"changeID,omitempty"`
+	Change             string   `json:"change,omitempty"`
+	PropagationSeconds *float64 `json:"propagationSeconds,omitempty"`
+	Conflict           string   `json:"conflict,omitempty"`
+	// result of the adoption of an existing record set: adopted, differs with the differences or conflict with the other owner
+	Adoption string `json:"adoption,omitempty"`
+	// reason why the host is no valid DNS name, no record set is written for it
+	Invalid string `json:"invalid,omitempty"`
+	// "not found" if the load balancer of the host was not found, cleared by the next change of its record set
+	LoadBalancer string `json:"loadBalancer,omitempty"`
+}
+
+// create an event recorder which sends events to the kubernetes api
+func newEventRecorder(kclient kubernetes.Interface) record.EventRecorder {
+	broadcaster := record.NewBroadcaster()
+	broadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: kclient.CoreV1().Events("")})
+	return broadcaster.NewRecorder(scheme.Scheme, corev1.EventSource{Component: "AmazonRoute53-ingress-controller"})
+}
+
+// sourceStatus is the status of a resource as last written by the controller, which is the only writer of it.
+// Statuses are kept in memory, so writing them needs no GET of the resource.
+type sourceStatus struct {
+	// latest version of the resource passed to the controller
+	source recordSource
+	// statuses of the hosts of ingress resources, services, gateways and virtual services. Their status has no fields for
+	// them, so they are written to the status annotation.
+	hosts map[string]*hostStatus
+	// status of the change and the Ready condition of a DNSRecord, which are written to its status subresource
+	record    *hostStatus
+	condition *recordCondition
+}
+
+// recordCondition is the Ready condition of a DNSRecord
+type recordCondition struct {
+	ready   bool
+	reason  string
+	message string
+}
+
+// create the queue of the resources whose status has to be written, all together with a limited rate and each with
+// backoff until it is written
+func newStatusQueue() workqueue.RateLimitingInterface {
+	return workqueue.NewNamedRateLimitingQueue(workqueue.NewMaxOfRateLimiter(
+		workqueue.NewItemExponentialFailureRateLimiter(minStatusWriteInterval, maxStatusWriteInterval),
+		&workqueue.BucketRateLimiter{Limiter: rate.NewLimiter(rate.Limit(statusWriteRate), statusWriteBurst)},
+	), "statuses")
+}
+
+// RunStatusWriter writes the queued statuses of the resources until stopCh is closed
+func (c *Controller) RunStatusWriter(stopCh <-chan struct{}, wg *sync.WaitGroup) {
+	defer wg.Done()
+
+	go func() {
+		<-stopCh
+		c.statusQueue.ShutDown()
+	}()
+	for c.writeNextStatus() {
+	}
+}
+
+// update the status of a host of the given resource in memory and queue writing it
+func (c *Controller) setHostStatus(sourceObj recordSource, host string, update func(status *hostStatus)) {
+	c.statusMutex.Lock()
+	current := c.sourceStatus(sourceObj)
+	if recordObj, ok := sourceObj.(*unstructured.Unstructured); ok && recordObj.GroupVersionKind().Group == dnsRecordGroup {
+		// the Ready condition of a DNSRecord follows the status of its change
+		update(current.record)
+		current.condition = &recordCondition{ready: false, reason: "Pending", message: "Route53 change " + current.record.ChangeID + " is pending"}
+		if current.record.Change == route53.ChangeStatusInsync {
+			current.condition = &recordCondition{ready: true, reason: "InSync", message: "Route53 change " + current.record.ChangeID + " is in sync"}
+		}
+	} else {
+		if current.hosts[host] == nil {
+			current.hosts[host] = &hostStatus{}
+		}
+		update(current.hosts[host])
+	}
+	c.statusMutex.Unlock()
+
+	c.statusQueue.AddRateLimited(sourceKey(sourceObj))
+}
+
+// set the Ready condition of the DNSRecord in memory and queue writing it
+func (c *Controller) setRecordCondition(recordObj *unstructured.Unstructured, ready bool, reason, message string) {
+	c.statusMutex.Lock()
+	c.sourceStatus(recordObj).condition = &recordCondition{ready: ready, reason: reason, message: message}
+	c.statusMutex.Unlock()
+
+	c.statusQueue.AddRateLimited(sourceKey(recordObj))
+}
+
+// return the status of the resource kept in memory, it is initialized from the resource the first time and
+// after the resource was recreated. Must be called with statusMutex held.
+func (c *Controller) sourceStatus(sourceObj recordSource) *sourceStatus {
+	key := sourceKey(sourceObj)
+	current, ok := c.statuses[key]
+	if !ok || current.source.GetUID() != sourceObj.GetUID() {
+		current = &sourceStatus{hosts: map[string]*hostStatus{}, record: &hostStatus{}}
+		if recordObj, ok := sourceObj.(*unstructured.Unstructured); ok && recordObj.GroupVersionKind().Group == dnsRecordGroup {
+			current.record.ChangeID, _, _ = unstructured.NestedString(recordObj.Object, "status", "changeID")
+			current.record.Change, _, _ = unstructured.NestedString(recordObj.Object, "status", "change")
+		} else if value, ok := sourceObj.GetAnnotations()[statusAnnotation]; ok {
+			if err := json.Unmarshal([]byte(value), &current.hosts); err != nil {
+				level.Debug(c.logger).Log("msg", "Ignoring malformed status annotation", "err", err.Error(), "resource", key)
+				current.hosts = map[string]*hostStatus{}
+			}
+		}
+		c.statuses[key] = current
+	}
+	current.source = sourceObj
+	return current
+}
+
+// forget the status of the deleted resource, a queued write of it is skipped
+func (c *Controller) forgetStatus(sourceObj recordSource) {
+	c.statusMutex.Lock()
+	defer c.statusMutex.Unlock()
+	if current, ok := c.statuses[sourceKey(sourceObj)]; ok && current.source.GetUID() == sourceObj.GetUID() {
+		delete(c.statuses, sourceKey(sourceObj))
+	}
+}
+
+// write the status of the next queued resource, which is queued again with backoff if writing failed.
+// Returns false if the queue is shut down.
+func (c *Controller) writeNextStatus() bool {
+	item, shutdown := c.statusQueue.Get()
+	if shutdown {
+		return false
+	}
+	defer c.statusQueue.Done(item)
+
+	err := c.writeStatus(item.(string))
+	switch {
+	case err == nil:
+	case errors.IsNotFound(err) || errors.IsConflict(err) || errors.IsInvalid(err):
+		// the resource was deleted or recreated meanwhile, the recreated one gets its own status
+		level.Debug(c.logger).Log("msg", "Resource changed before its status was written, status skipped", "err", err.Error(), "resource", item)
+	default:
+		level.Warn(c.logger).Log("msg", "Could not update status of resource", "err", err.Error(), "resource", item)
+		c.statusQueue.AddRateLimited(item)
+		return true
+	}
+	c.statusQueue.Forget(item)
+	return true
+}
+
+// write the status of the resource kept in memory with a merge patch. The patch contains the UID of the resource,
+// so it is rejected if the resource was recreated meanwhile.
+func (c *Controller) writeStatus(key string) error {
+	c.statusMutex.Lock()
+	current, ok := c.statuses[key]
+	if !ok {
+		c.statusMutex.Unlock()
+		return nil
+	}
+	sourceObj := current.source
+	metadata := map[string]interface{}{"uid": sourceObj.GetUID()}
+	var patch []byte
+	if recordObj, ok := sourceObj.(*unstructured.Unstructured); ok && recordObj.GroupVersionKind().Group == dnsRecordGroup {
+		patch, _ = json.Marshal(map[string]interface{}{"metadata": metadata, "status": recordStatus(recordObj, current.record, current.condition)})
+	} else {
+		value, _ := json.Marshal(current.hosts)
+		metadata["annotations"] = map[string]string{statusAnnotation: string(value)}
+		patch, _ = json.Marshal(map[string]interface{}{"metadata": metadata})
+	}
+	c.statusMutex.Unlock()
+
+	return c.patchSource(sourceObj, patch)
+}
+
+// apply a merge patch to the resource
+func (c *Controller) patchSource(sourceObj recordSource, patch []byte) error {
+	var err error
+	switch obj := sourceObj.(type) {
+	case *corev1.Service:
+		_, err = c.kclient.CoreV1().Services(sourceObj.GetNamespace()).Patch(sourceObj.GetName(), types.MergePatchType, patch)
+	case *unstructured.Unstructured:
+		if obj.GroupVersionKind().Group == dnsRecordGroup {
+			_, err = c.dclient.Resource(DNSRecordResource).Namespace(sourceObj.GetNamespace()).Patch(sourceObj.GetName(), types.MergePatchType, patch, metav1.PatchOptions{}, "status")
+			break
+		}
+		resource := dynamicResources[obj.GroupVersionKind().GroupKind()]
+		_, err = c.dclient.Resource(resource).Namespace(sourceObj.GetNamespace()).Patch(sourceObj.GetName(), types.MergePatchType, patch, metav1.PatchOptions{})
+	default:
+		_, err = c.kclient.NetworkingV1beta1().Ingresses(sourceObj.GetNamespace()).Patch(sourceObj.GetName(), types.MergePatchType, patch)
+	}
+	return err
+}
@@ -0,0 +1,113 @@
+package controller
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/service/route53"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestStatusWriter(t *testing.T) {
+	c, _, cleanup := newZoneFileController(t, "")
+	defer cleanup()
+	service, err := c.kclient.CoreV1().Services("default").Create(&corev1.Service{ObjectMeta: metav1.ObjectMeta{
+		Namespace:   "default",
+		Name:        "app",
+		UID:         "app",
+		Annotations: map[string]string{statusAnnotation: `{"other.example.com":{"conflict":"owned by ingress/default/other"}}`},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	kclient := c.kclient.(*fake.Clientset)
+	kclient.ClearActions()
+
+	// the updates of a resource are written together with a single patch without getting the resource
+	c.setHostStatus(service, "app.example.com", func(status *hostStatus) {
+		status.ChangeID = "C1"
+	})
+	c.setHostStatus(service, "app.example.com", func(status *hostStatus) {
+		status.Change = route53.ChangeStatusPending
+	})
+	c.writeNextStatus()
+	var verbs []string
+	for _, action := range kclient.Actions() {
+		verbs = append(verbs, action.GetVerb())
+	}
+	if len(verbs) != 1 || verbs[0] != "patch" {
+		t.Errorf("got requests %v, want a single patch", verbs)
+	}
+	if status := hostStatusOf(t, c, service, "app.example.com"); status == nil || status.ChangeID != "C1" || status.Change != route53.ChangeStatusPending {
+		t.Errorf("got status %+v, want the pending change C1", status)
+	}
+	if status := hostStatusOf(t, c, service, "other.example.com"); status == nil || status.Conflict == "" {
+		t.Errorf("got status %+v of the other host, want its conflict to be kept", status)
+	}
+
+	// a recreated resource starts with its own status
+	if err := c.kclient.CoreV1().Services("default").Delete("app", nil); err != nil {
+		t.Fatal(err)
+	}
+	recreated := newStatusSource(t, c, "app")
+	recreated.UID = types.UID("recreated")
+	c.setHostStatus(recreated, "new.example.com", func(status *hostStatus) {
+		status.ChangeID = "C2"
+	})
+	if status := hostStatusOf(t, c, recreated, "app.example.com"); status != nil {
+		t.Errorf("got status %+v of the deleted resource, want none", status)
+	}
+
+	// the status of a deleted resource is skipped and not written again
+	if err := c.kclient.CoreV1().Services("default").Delete("app", nil); err != nil {
+		t.Fatal(err)
+	}
+	c.writeNextStatus()
+	if requeues := c.statusQueue.NumRequeues("service/default/app"); requeues != 0 {
+		t.Errorf("got %d requeues of the deleted resource, want none", requeues)
+	}
+}
+
+func TestRecordStatus(t *testing.T) {
+	c, _, cleanup := newZoneFileController(t, "")
+	defer cleanup()
+	recordObj := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": dnsRecordGroup + "/v1alpha1",
+		"kind":       "DNSRecord",
+		"metadata":   map[string]interface{}{"namespace": "default", "name": "app", "uid": "app"},
+	}}
+	c.UseDynamicClient(dynamicfake.NewSimpleDynamicClient(runtime.NewScheme(), recordObj))
+	condition := func() (string, string, string) {
+		writeStatuses(t, c)
+		current, err := c.dclient.Resource(DNSRecordResource).Namespace("default").Get("app", metav1.GetOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		changeID, _, _ := unstructured.NestedString(current.Object, "status", "changeID")
+		conditions, _, _ := unstructured.NestedSlice(current.Object, "status", "conditions")
+		if len(conditions) != 1 {
+			t.Fatalf("got conditions %v, want the Ready condition", conditions)
+		}
+		ready := conditions[0].(map[string]interface{})
+		return ready["status"].(string), ready["reason"].(string), changeID
+	}
+
+	c.setRecordCondition(recordObj, false, "PolicySkipped", "record set exists already")
+	if status, reason, changeID := condition(); status != "False" || reason != "PolicySkipped" || changeID != "" {
+		t.Errorf("got Ready %s (%s) with change %q, want False (PolicySkipped) without change", status, reason, changeID)
+	}
+
+	// the Ready condition follows the status of the change
+	c.setHostStatus(recordObj, "app.example.com", func(status *hostStatus) {
+		status.ChangeID = "C1"
+		status.Change = route53.ChangeStatusInsync
+	})
+	if status, reason, changeID := condition(); status != "True" || reason != "InSync" || changeID != "C1" {
+		t.Errorf("got Ready %s (%s) with change %q, want True (InSync) with change C1", status, reason, changeID)
+	}
+}
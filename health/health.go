@@ -0,0 +1,97 @@
+package health
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/dbsystel/AmazonRoute53-ingress-controller/provider"
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"k8s.io/client-go/tools/cache"
+)
+
+// Checker serves liveness and readiness probes for the controller
+type Checker struct {
+	logger          log.Logger
+	checkInterval   time.Duration
+	readinessWindow time.Duration
+	livenessTimeout time.Duration
+	synced          []cache.InformerSynced
+	provider        provider.Provider
+	stalled         func(timeout time.Duration) bool
+
+	mutex               sync.RWMutex
+	lastProviderSuccess time.Time
+}
+
+// New creates a new object from type Checker and return object pointer
+func New(logger log.Logger, dnsProvider provider.Provider, checkInterval time.Duration, readinessWindow time.Duration, livenessTimeout time.Duration, stalled func(timeout time.Duration) bool, synced ...cache.InformerSynced) *Checker {
+	checker := &Checker{}
+	checker.logger = logger
+	checker.provider = dnsProvider
+	checker.checkInterval = checkInterval
+	checker.readinessWindow = readinessWindow
+	checker.livenessTimeout = livenessTimeout
+	checker.stalled = stalled
+	checker.synced = synced
+	return checker
+}
+
+// Run checks the DNS provider connectivity periodically until stopCh is closed
+func (h *Checker) Run(stopCh <-chan struct{}, wg *sync.WaitGroup) {
+	defer wg.Done()
+
+	ticker := time.NewTicker(h.checkInterval)
+	defer ticker.Stop()
+
+	for {
+		h.checkProvider()
+		select {
+		case <-ticker.C:
+		case <-stopCh:
+			return
+		}
+	}
+}
+
+// check whether the DNS provider is reachable and remember the time of success
+func (h *Checker) checkProvider() {
+	if err := h.provider.CheckConnectivity(); err != nil {
+		level.Warn(h.logger).Log("msg", "DNS provider connectivity check failed", "err", err.Error())
+		return
+	}
+	h.mutex.Lock()
+	h.lastProviderSuccess = time.Now()
+	h.mutex.Unlock()
+}
+
+// Healthz reports whether the controller is still handling events
+func (h *Checker) Healthz(w http.ResponseWriter, r *http.Request) {
+	if h.stalled(h.livenessTimeout) {
+		http.Error(w, fmt.Sprintf("handling of a single event takes longer than %s", h.livenessTimeout), http.StatusServiceUnavailable)
+		return
+	}
+	fmt.Fprintln(w, "ok")
+}
+
+// Readyz reports whether all informers have synced and the DNS provider was reachable recently
+func (h *Checker) Readyz(w http.ResponseWriter, r *http.Request) {
+	for _, synced := range h.synced {
+		if !synced() {
+			http.Error(w, "informer caches have not synced yet", http.StatusServiceUnavailable)
+			return
+		}
+	}
+
+	h.mutex.RLock()
+	lastProviderSuccess := h.lastProviderSuccess
+	h.mutex.RUnlock()
+
+	if time.Since(lastProviderSuccess) > h.readinessWindow {
+		http.Error(w, fmt.Sprintf("no successful DNS provider connectivity check within the last %s", h.readinessWindow), http.StatusServiceUnavailable)
+		return
+	}
+	fmt.Fprintln(w, "ok")
+}
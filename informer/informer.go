@@ -0,0 +1,140 @@
+package informer
+
+import (
+	"sync"
+	"time"
+
+	"github.com/dbsystel/kube-controller-dbsystel-go-common/controller"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/api/networking/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+)
+
+// Informer watches kubernetes resources, passes their events to a controller and reports whether its cache has synced
+type Informer struct {
+	informer cache.SharedIndexInformer
+}
+
+// New creates a new object from type Informer for the given list watch and return object pointer.
+// If ctrl is nil, the informer only caches the resources.
+func New(listWatch cache.ListerWatcher, objType runtime.Object, ctrl controller.Controller) *Informer {
+	informer := cache.NewSharedIndexInformer(
+		listWatch,
+		objType,
+		3*time.Minute,
+		cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc},
+	)
+
+	i := &Informer{informer: informer}
+	if ctrl != nil {
+		i.AddController(ctrl)
+	}
+	return i
+}
+
+// NewIngressInformer creates a new Informer watching ingress resources in all namespaces
+func NewIngressInformer(kclient kubernetes.Interface, ctrl controller.Controller) *Informer {
+	return New(
+		&cache.ListWatch{
+			ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+				return kclient.NetworkingV1beta1().Ingresses(metav1.NamespaceAll).List(options)
+			},
+			WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+				return kclient.NetworkingV1beta1().Ingresses(metav1.NamespaceAll).Watch(options)
+			},
+		},
+		&v1beta1.Ingress{},
+		ctrl,
+	)
+}
+
+// NewServiceInformer creates a new Informer watching services in all namespaces
+func NewServiceInformer(kclient kubernetes.Interface, ctrl controller.Controller) *Informer {
+	return New(
+		&cache.ListWatch{
+			ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+				return kclient.CoreV1().Services(metav1.NamespaceAll).List(options)
+			},
+			WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+				return kclient.CoreV1().Services(metav1.NamespaceAll).Watch(options)
+			},
+		},
+		&corev1.Service{},
+		ctrl,
+	)
+}
+
+// NewNamespaceInformer creates a new Informer caching all namespaces
+func NewNamespaceInformer(kclient kubernetes.Interface) *Informer {
+	return New(
+		&cache.ListWatch{
+			ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+				return kclient.CoreV1().Namespaces().List(options)
+			},
+			WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+				return kclient.CoreV1().Namespaces().Watch(options)
+			},
+		},
+		&corev1.Namespace{},
+		nil,
+	)
+}
+
+// NewDynamicInformer creates a new Informer watching the given custom resource in all namespaces
+func NewDynamicInformer(dclient dynamic.Interface, resource schema.GroupVersionResource, ctrl controller.Controller) *Informer {
+	return New(
+		&cache.ListWatch{
+			ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+				return dclient.Resource(resource).Namespace(metav1.NamespaceAll).List(options)
+			},
+			WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+				return dclient.Resource(resource).Namespace(metav1.NamespaceAll).Watch(options)
+			},
+		},
+		&unstructured.Unstructured{},
+		ctrl,
+	)
+}
+
+// AddController passes the events of the informer to another controller as well, existing resources are passed as created
+func (i *Informer) AddController(ctrl controller.Controller) {
+	i.informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    ctrl.Create,
+		UpdateFunc: ctrl.Update,
+		DeleteFunc: func(obj interface{}) {
+			ctrl.Delete(deletedObject(obj))
+		},
+	})
+}
+
+// return the deleted resource, which is wrapped in a tombstone if its deletion was missed while the watch was interrupted
+func deletedObject(obj interface{}) interface{} {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		return tombstone.Obj
+	}
+	return obj
+}
+
+// Run starts the informer and blocks until stopCh is closed
+func (i *Informer) Run(stopCh <-chan struct{}, wg *sync.WaitGroup) {
+	defer wg.Done()
+
+	i.informer.Run(stopCh)
+}
+
+// HasSynced returns true once the initial list of resources has been delivered to the controller
+func (i *Informer) HasSynced() bool {
+	return i.informer.HasSynced()
+}
+
+// Indexer returns the cache of the informer, e.g. for a lister
+func (i *Informer) Indexer() cache.Indexer {
+	return i.informer.GetIndexer()
+}
@@ -0,0 +1,25 @@
+package informer
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+func TestDeletedObject(t *testing.T) {
+	service := &corev1.Service{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "app"}}
+	tests := []struct {
+		name string
+		obj  interface{}
+	}{
+		{"resource", service},
+		{"tombstone", cache.DeletedFinalStateUnknown{Key: "default/app", Obj: service}},
+	}
+	for _, test := range tests {
+		if obj, ok := deletedObject(test.obj).(*corev1.Service); !ok || obj != service {
+			t.Errorf("%s: got %#v, want the service", test.name, obj)
+		}
+	}
+}
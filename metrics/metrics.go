@@ -0,0 +1,179 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const namespace = "route53_ingress_controller"
+
+var (
+	// Route53Changes counts submitted Amazon Route53 record set changes
+	Route53Changes = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "route53_changes_total",
+		Help:      "Number of Amazon Route53 record set changes by action, record type and result.",
+	}, []string{"action", "type", "result"})
+
+	// PolicySkipped counts Amazon Route53 changes skipped by the policy
+	PolicySkipped = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "policy_skipped_changes_total",
+		Help:      "Number of Amazon Route53 changes skipped by the policy by policy and action.",
+	}, []string{"policy", "action"})
+
+	// AWSAPIErrors counts errors returned by the AWS API by error code
+	AWSAPIErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "aws_api_errors_total",
+		Help:      "Number of errors returned by the AWS API by error code.",
+	}, []string{"code"})
+
+	// HostsManaged is the number of hosts currently managed by the controller
+	HostsManaged = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "hosts_managed",
+		Help:      "Number of hosts currently referenced by at least one ingress resource.",
+	})
+
+	// HostsSkipped counts hosts skipped because they are not in the allowlist
+	HostsSkipped = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "hosts_skipped_total",
+		Help:      "Number of hosts skipped because they are not in the allowlist, by operation.",
+	}, []string{"operation"})
+
+	// HostsNotDelegated counts hosts skipped because they are not granted to the namespace of the ingress resource
+	HostsNotDelegated = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "hosts_not_delegated_total",
+		Help:      "Number of hosts skipped because they are not granted to the namespace of the ingress resource, by operation.",
+	}, []string{"operation"})
+
+	// HostsInvalid counts hosts skipped because they are no valid DNS names
+	HostsInvalid = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "hosts_invalid_total",
+		Help:      "Number of hosts skipped because they are no valid DNS names, by operation.",
+	}, []string{"operation"})
+
+	// HostReferenceCounterSize is the number of references from ingress resources to hosts
+	HostReferenceCounterSize = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "host_reference_counter_size",
+		Help:      "Number of references from ingress resources to hosts.",
+	})
+
+	// ActiveConflicts is the number of hosts referenced by ingress resources pointing to different load balancers
+	ActiveConflicts = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "active_conflicts",
+		Help:      "Number of hosts referenced by ingress resources pointing to different load balancers.",
+	})
+
+	// ReconcileDuration observes how long handling an ingress event took
+	ReconcileDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "reconcile_duration_seconds",
+		Help:      "Duration of handling an ingress resource event by operation.",
+		Buckets:   prometheus.ExponentialBuckets(0.05, 2, 10),
+	}, []string{"operation"})
+
+	// LastSuccessfulSync is the unix timestamp of the last successful Amazon Route53 change
+	LastSuccessfulSync = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "last_successful_sync_timestamp_seconds",
+		Help:      "Unix timestamp of the last successful Amazon Route53 record set change.",
+	})
+
+	// PendingChanges is the number of submitted Amazon Route53 changes which are not INSYNC yet
+	PendingChanges = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "pending_changes",
+		Help:      "Number of submitted Amazon Route53 changes which are not INSYNC yet.",
+	})
+
+	// PropagationDuration observes how long Amazon Route53 changes took to become INSYNC
+	PropagationDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "propagation_duration_seconds",
+		Help:      "Duration until a submitted Amazon Route53 change became INSYNC by action.",
+		Buckets:   prometheus.ExponentialBuckets(5, 2, 8),
+	}, []string{"action"})
+
+	// ConfigReloads counts reloads of the configuration file by result
+	ConfigReloads = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "config_reloads_total",
+		Help:      "Number of reloads of the configuration file by result.",
+	}, []string{"result"})
+
+	// ConfigLastReloadSuccessful is 1 if the last reload of the configuration file was successful
+	ConfigLastReloadSuccessful = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "config_last_reload_successful",
+		Help:      "Whether the last reload of the configuration file was successful.",
+	})
+
+	// ConfigLastReloadSuccessTimestamp is the unix timestamp of the last successful reload of the configuration file
+	ConfigLastReloadSuccessTimestamp = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "config_last_reload_success_timestamp_seconds",
+		Help:      "Unix timestamp of the last successful reload of the configuration file.",
+	})
+
+	// HeldDeletions is the number of record set deletions currently held back by the mass deletion guard
+	HeldDeletions = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "held_deletions",
+		Help:      "Number of record set deletions currently held back by the mass deletion guard.",
+	})
+
+	// HeldDeletionsTotal counts record set deletions held back by the mass deletion guard
+	HeldDeletionsTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "held_deletions_total",
+		Help:      "Number of record set deletions held back by the mass deletion guard.",
+	})
+
+	// HeldDeletionsCompletedTotal counts held back record set deletions which were executed later
+	HeldDeletionsCompletedTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "held_deletions_completed_total",
+		Help:      "Number of held back record set deletions which were executed later.",
+	})
+
+	// OrphanedRecords counts orphaned record sets found by the garbage collection by result
+	OrphanedRecords = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "orphaned_records_total",
+		Help:      "Number of orphaned record sets found by the garbage collection by result (deleted, dry_run, held, skipped, error).",
+	}, []string{"result"})
+
+	// Adoptions counts existing record sets found in adoption mode by result
+	Adoptions = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "adoptions_total",
+		Help:      "Number of existing record sets not owned by the controller found in adoption mode by result (adopted, differs, conflict, error).",
+	}, []string{"result"})
+
+	// DriftedRecords is the number of record sets found drifted by the last drift detection
+	DriftedRecords = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "drifted_records",
+		Help:      "Number of managed record sets whose live values differed from the desired ones at the last drift detection.",
+	})
+	// DriftedRecordsTotal counts drifted record sets by result
+	DriftedRecordsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "drifted_records_total",
+		Help:      "Number of drifted record sets found by the drift detection by result (detected, fixed, dry_run, not_fixed).",
+	}, []string{"result"})
+
+	// LoadBalancerChanges counts load balancers found changed or deleted by the load balancer watcher
+	LoadBalancerChanges = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "load_balancer_changes_total",
+		Help:      "Number of load balancers found changed or deleted by the load balancer watcher by change (changed, deleted).",
+	}, []string{"change"})
+)
@@ -0,0 +1,46 @@
+package provider
+
+import (
+	"strings"
+
+	"github.com/aws/aws-sdk-go/service/route53"
+)
+
+// Provider is a DNS service whose zones are managed by the controller.
+// Record sets and changes are given as Amazon Route53 types, which every provider translates into its own.
+type Provider interface {
+	// Zones returns all zones, listed with the given IAM role if roleARN is not empty
+	Zones(roleARN string) ([]Zone, error)
+	// RecordSets returns all record sets with the given name in the zone
+	RecordSets(zoneID, roleARN, name string) ([]*route53.ResourceRecordSet, error)
+	// ListRecordSets returns all record sets of the zone
+	ListRecordSets(zoneID, roleARN string) ([]*route53.ResourceRecordSet, error)
+	// ApplyChanges submits the changes as one batch to the zone and returns the ID of the submitted change
+	ApplyChanges(zoneID, roleARN string, changes []*route53.Change) (string, error)
+	// ChangeStatus returns the status (PENDING/INSYNC) of a submitted change
+	ChangeStatus(changeID, roleARN string) (string, error)
+	// CheckConnectivity verifies that the DNS service is reachable
+	CheckConnectivity() error
+	// AliasRecords reports whether ALIAS records to AWS load balancers are supported
+	AliasRecords() bool
+}
+
+// Zone is a zone of a provider
+type Zone struct {
+	// name without trailing dot
+	Name string
+	ID   string
+}
+
+// FindZone returns the zone for the host, which is the zone with the longest name matching the host
+func FindZone(zones []Zone, host string) (Zone, bool) {
+	var found Zone
+	for _, zone := range zones {
+		if host == zone.Name || strings.HasSuffix(host, "."+zone.Name) {
+			if len(zone.Name) > len(found.Name) {
+				found = zone
+			}
+		}
+	}
+	return found, found.ID != ""
+}
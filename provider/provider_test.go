@@ -0,0 +1,24 @@
+package provider
+
+import "testing"
+
+func TestFindZone(t *testing.T) {
+	zones := []Zone{{Name: "example.com", ID: "Z1"}, {Name: "sub.example.com", ID: "Z2"}}
+	tests := []struct {
+		host string
+		id   string
+	}{
+		{"example.com", "Z1"},
+		{"app.example.com", "Z1"},
+		{"sub.example.com", "Z2"},
+		{"app.sub.example.com", "Z2"},
+		{"appsub.example.com", "Z1"},
+		{"example.org", ""},
+	}
+	for _, test := range tests {
+		zone, found := FindZone(zones, test.host)
+		if zone.ID != test.id || found != (test.id != "") {
+			t.Errorf("%s: got zone %q, %v, want %q", test.host, zone.ID, found, test.id)
+		}
+	}
+}
@@ -0,0 +1,254 @@
+package rfc2136
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	awssdk "github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/route53"
+	"github.com/dbsystel/AmazonRoute53-ingress-controller/provider"
+	"github.com/miekg/dns"
+)
+
+// Algorithms are the supported TSIG algorithms
+var Algorithms = []string{"hmac-md5", "hmac-sha1", "hmac-sha256", "hmac-sha512"}
+
+// Config defines the name server and the zones managed by dynamic updates
+type Config struct {
+	// address of the primary name server, e.g. ns1.example.com:53
+	Server string
+	// names of the zones which may be updated
+	Zones []string
+	// TSIG key, updates are not signed if the key name is empty
+	TSIGKeyName   string
+	TSIGSecret    string
+	TSIGAlgorithm string
+	Timeout       time.Duration
+}
+
+// RFC2136 is the provider for name servers supporting dynamic updates (RFC 2136), e.g. BIND
+type RFC2136 struct {
+	config Config
+	// number of submitted updates, used as change ID
+	changes uint64
+}
+
+// New returns the provider for the name server of the given config
+func New(config Config) (provider.Provider, error) {
+	if config.Server == "" {
+		return nil, errors.New("the name server for RFC2136 updates is missing")
+	}
+	if len(config.Zones) == 0 {
+		return nil, errors.New("at least one zone for RFC2136 updates is required")
+	}
+	if _, _, err := net.SplitHostPort(config.Server); err != nil {
+		return nil, fmt.Errorf("the name server must be given as host:port: %v", err)
+	}
+	if config.TSIGKeyName != "" && config.TSIGSecret == "" {
+		return nil, errors.New("the TSIG secret of key " + config.TSIGKeyName + " is missing")
+	}
+	return &RFC2136{config: config}, nil
+}
+
+// Zones returns the configured zones, their ID is their name
+func (r *RFC2136) Zones(roleARN string) ([]provider.Zone, error) {
+	var zones []provider.Zone
+	for _, zone := range r.config.Zones {
+		name := strings.ToLower(strings.TrimSuffix(zone, "."))
+		zones = append(zones, provider.Zone{Name: name, ID: name})
+	}
+	return zones, nil
+}
+
+// RecordSets returns all record sets with the given name in the zone, read by a zone transfer. Queries are not used,
+// because a wildcard of the zone makes the name server synthesize answers for names without record sets.
+func (r *RFC2136) RecordSets(zoneID, roleARN, name string) ([]*route53.ResourceRecordSet, error) {
+	rrs, err := r.transfer(zoneID)
+	if err != nil {
+		return nil, err
+	}
+	return FromRRs(named(rrs, name)), nil
+}
+
+// return the resource records with the given owner name, the records of a matching wildcard are not included
+func named(rrs []dns.RR, name string) []dns.RR {
+	var named []dns.RR
+	for _, rr := range rrs {
+		if strings.EqualFold(rr.Header().Name, dns.Fqdn(name)) {
+			named = append(named, rr)
+		}
+	}
+	return named
+}
+
+// ListRecordSets returns all record sets of the zone, read by a zone transfer
+func (r *RFC2136) ListRecordSets(zoneID, roleARN string) ([]*route53.ResourceRecordSet, error) {
+	rrs, err := r.transfer(zoneID)
+	if err != nil {
+		return nil, err
+	}
+	return FromRRs(rrs), nil
+}
+
+// return the resource records of the zone without its SOA record by a zone transfer
+func (r *RFC2136) transfer(zoneID string) ([]dns.RR, error) {
+	msg := new(dns.Msg)
+	msg.SetAxfr(dns.Fqdn(zoneID))
+	r.sign(msg)
+
+	transfer := &dns.Transfer{DialTimeout: r.config.Timeout, ReadTimeout: r.config.Timeout, TsigSecret: r.tsigSecret()}
+	envelopes, err := transfer.In(msg, r.config.Server)
+	if err != nil {
+		return nil, err
+	}
+
+	var rrs []dns.RR
+	for envelope := range envelopes {
+		if envelope.Error != nil {
+			return nil, envelope.Error
+		}
+		for _, rr := range envelope.RR {
+			if rr.Header().Rrtype != dns.TypeSOA {
+				rrs = append(rrs, rr)
+			}
+		}
+	}
+	return rrs, nil
+}
+
+// ApplyChanges submits the changes as one dynamic update to the zone, which is applied atomically by the name server
+func (r *RFC2136) ApplyChanges(zoneID, roleARN string, changes []*route53.Change) (string, error) {
+	msg := new(dns.Msg)
+	msg.SetUpdate(dns.Fqdn(zoneID))
+	for _, change := range changes {
+		rrs, err := ToRRs(change.ResourceRecordSet)
+		if err != nil {
+			return "", err
+		}
+		switch *change.Action {
+		case route53.ChangeActionCreate:
+			// like Route53, a CREATE fails if the record set exists already
+			msg.RRsetNotUsed(rrs[:1])
+			msg.Insert(rrs)
+		case route53.ChangeActionUpsert:
+			msg.RemoveRRset(rrs[:1])
+			msg.Insert(rrs)
+		case route53.ChangeActionDelete:
+			msg.Remove(rrs)
+		default:
+			return "", fmt.Errorf("unsupported change action %s", *change.Action)
+		}
+	}
+	r.sign(msg)
+
+	response, err := r.exchange(msg)
+	if err != nil {
+		return "", err
+	}
+	if response.Rcode != dns.RcodeSuccess {
+		return "", fmt.Errorf("dynamic update of zone %s failed: %s", zoneID, dns.RcodeToString[response.Rcode])
+	}
+	return "rfc2136-" + strconv.FormatUint(atomic.AddUint64(&r.changes, 1), 10), nil
+}
+
+// ChangeStatus returns INSYNC, because dynamic updates are applied by the primary name server before it responds
+func (r *RFC2136) ChangeStatus(changeID, roleARN string) (string, error) {
+	return route53.ChangeStatusInsync, nil
+}
+
+// CheckConnectivity verifies that the name server answers authoritatively for all zones
+func (r *RFC2136) CheckConnectivity() error {
+	for _, zone := range r.config.Zones {
+		msg := new(dns.Msg)
+		msg.SetQuestion(dns.Fqdn(zone), dns.TypeSOA)
+		r.sign(msg)
+		response, err := r.exchange(msg)
+		if err != nil {
+			return err
+		}
+		if response.Rcode != dns.RcodeSuccess || !response.Authoritative {
+			return fmt.Errorf("name server %s is not authoritative for zone %s: %s", r.config.Server, zone, dns.RcodeToString[response.Rcode])
+		}
+	}
+	return nil
+}
+
+// AliasRecords reports that ALIAS records are not supported
+func (r *RFC2136) AliasRecords() bool {
+	return false
+}
+
+// send the message to the name server over TCP
+func (r *RFC2136) exchange(msg *dns.Msg) (*dns.Msg, error) {
+	client := &dns.Client{Net: "tcp", Timeout: r.config.Timeout, TsigSecret: r.tsigSecret()}
+	response, _, err := client.Exchange(msg, r.config.Server)
+	return response, err
+}
+
+// sign the message with the TSIG key, if one is configured
+func (r *RFC2136) sign(msg *dns.Msg) {
+	if r.config.TSIGKeyName == "" {
+		return
+	}
+	msg.SetTsig(dns.Fqdn(r.config.TSIGKeyName), dns.Fqdn(r.config.TSIGAlgorithm), 300, time.Now().Unix())
+}
+
+// return the TSIG secrets by key name as required by the dns client
+func (r *RFC2136) tsigSecret() map[string]string {
+	if r.config.TSIGKeyName == "" {
+		return nil
+	}
+	return map[string]string{dns.Fqdn(r.config.TSIGKeyName): r.config.TSIGSecret}
+}
+
+// FromRRs groups the resource records into record sets by name and type
+func FromRRs(rrs []dns.RR) []*route53.ResourceRecordSet {
+	var resourceRecordSets []*route53.ResourceRecordSet
+	byNameAndType := make(map[string]*route53.ResourceRecordSet)
+	for _, rr := range rrs {
+		header := rr.Header()
+		key := strings.ToLower(header.Name) + "/" + dns.TypeToString[header.Rrtype]
+		resourceRecordSet, ok := byNameAndType[key]
+		if !ok {
+			resourceRecordSet = &route53.ResourceRecordSet{
+				Name: awssdk.String(header.Name),
+				Type: awssdk.String(dns.TypeToString[header.Rrtype]),
+				TTL:  awssdk.Int64(int64(header.Ttl)),
+			}
+			byNameAndType[key] = resourceRecordSet
+			resourceRecordSets = append(resourceRecordSets, resourceRecordSet)
+		}
+		value := strings.TrimPrefix(rr.String(), header.String())
+		resourceRecordSet.ResourceRecords = append(resourceRecordSet.ResourceRecords, &route53.ResourceRecord{Value: awssdk.String(value)})
+	}
+	return resourceRecordSets
+}
+
+// ToRRs converts the record set into resource records, ALIAS records and routing policies are specific to Route53
+func ToRRs(resourceRecordSet *route53.ResourceRecordSet) ([]dns.RR, error) {
+	name := dns.Fqdn(*resourceRecordSet.Name)
+	if resourceRecordSet.AliasTarget != nil {
+		return nil, errors.New("ALIAS record " + name + " is specific to Amazon Route53")
+	}
+	if resourceRecordSet.SetIdentifier != nil {
+		return nil, errors.New("routing policy of record " + name + " is specific to Amazon Route53")
+	}
+	if len(resourceRecordSet.ResourceRecords) == 0 {
+		return nil, errors.New("record " + name + " has no values")
+	}
+
+	var rrs []dns.RR
+	for _, resourceRecord := range resourceRecordSet.ResourceRecords {
+		rr, err := dns.NewRR(fmt.Sprintf("%s %d IN %s %s", name, awssdk.Int64Value(resourceRecordSet.TTL), *resourceRecordSet.Type, *resourceRecord.Value))
+		if err != nil {
+			return nil, err
+		}
+		rrs = append(rrs, rr)
+	}
+	return rrs, nil
+}
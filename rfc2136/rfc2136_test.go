@@ -0,0 +1,185 @@
+package rfc2136
+
+import (
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	awssdk "github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/route53"
+	"github.com/miekg/dns"
+)
+
+func TestToRRs(t *testing.T) {
+	tests := []struct {
+		name   string
+		set    *route53.ResourceRecordSet
+		rrs    []string
+		failed bool
+	}{
+		{
+			name: "cname",
+			set:  &route53.ResourceRecordSet{Name: awssdk.String("app.example.com"), Type: awssdk.String("CNAME"), TTL: awssdk.Int64(300), ResourceRecords: []*route53.ResourceRecord{{Value: awssdk.String("lb.example.net.")}}},
+			rrs:  []string{"app.example.com.\t300\tIN\tCNAME\tlb.example.net."},
+		},
+		{
+			name: "txt with two values",
+			set:  &route53.ResourceRecordSet{Name: awssdk.String("_r53-ingress-owner.app.example.com."), Type: awssdk.String("TXT"), TTL: awssdk.Int64(60), ResourceRecords: []*route53.ResourceRecord{{Value: awssdk.String(`"a"`)}, {Value: awssdk.String(`"b"`)}}},
+			rrs:  []string{"_r53-ingress-owner.app.example.com.\t60\tIN\tTXT\t\"a\"", "_r53-ingress-owner.app.example.com.\t60\tIN\tTXT\t\"b\""},
+		},
+		{
+			name:   "alias",
+			set:    &route53.ResourceRecordSet{Name: awssdk.String("app.example.com"), Type: awssdk.String("A"), AliasTarget: &route53.AliasTarget{DNSName: awssdk.String("lb.example.net")}},
+			failed: true,
+		},
+		{
+			name:   "routing policy",
+			set:    &route53.ResourceRecordSet{Name: awssdk.String("app.example.com"), Type: awssdk.String("A"), SetIdentifier: awssdk.String("a"), ResourceRecords: []*route53.ResourceRecord{{Value: awssdk.String("192.0.2.1")}}},
+			failed: true,
+		},
+		{
+			name:   "no values",
+			set:    &route53.ResourceRecordSet{Name: awssdk.String("app.example.com"), Type: awssdk.String("A")},
+			failed: true,
+		},
+		{
+			name:   "invalid value",
+			set:    &route53.ResourceRecordSet{Name: awssdk.String("app.example.com"), Type: awssdk.String("A"), ResourceRecords: []*route53.ResourceRecord{{Value: awssdk.String("not-an-ip")}}},
+			failed: true,
+		},
+	}
+	for _, test := range tests {
+		rrs, err := ToRRs(test.set)
+		if (err != nil) != test.failed {
+			t.Errorf("%s: got error %v, want failure %v", test.name, err, test.failed)
+			continue
+		}
+		if len(rrs) != len(test.rrs) {
+			t.Errorf("%s: got %v, want %v", test.name, rrs, test.rrs)
+			continue
+		}
+		for i, rr := range rrs {
+			if rr.String() != test.rrs[i] {
+				t.Errorf("%s: got %q, want %q", test.name, rr.String(), test.rrs[i])
+			}
+		}
+	}
+}
+
+func TestFromRRs(t *testing.T) {
+	var rrs []dns.RR
+	for _, record := range []string{
+		"app.example.com. 300 IN A 192.0.2.1",
+		"App.Example.com. 300 IN A 192.0.2.2",
+		"app.example.com. 60 IN TXT \"owner\"",
+		"www.example.com. 300 IN CNAME app.example.com.",
+	} {
+		rr, err := dns.NewRR(record)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rrs = append(rrs, rr)
+	}
+
+	tests := []struct {
+		name   string
+		ttl    int64
+		values []string
+	}{
+		{"app.example.com./A", 300, []string{"192.0.2.1", "192.0.2.2"}},
+		{"app.example.com./TXT", 60, []string{`"owner"`}},
+		{"www.example.com./CNAME", 300, []string{"app.example.com."}},
+	}
+	sets := FromRRs(rrs)
+	if len(sets) != len(tests) {
+		t.Fatalf("got %d record sets, want %d", len(sets), len(tests))
+	}
+	for i, test := range tests {
+		set := sets[i]
+		if name := strings.ToLower(*set.Name) + "/" + *set.Type; name != test.name || *set.TTL != test.ttl {
+			t.Errorf("record set %d: got %s with ttl %d, want %s with ttl %d", i, name, *set.TTL, test.name, test.ttl)
+			continue
+		}
+		if len(set.ResourceRecords) != len(test.values) {
+			t.Errorf("%s: got %d values, want %v", test.name, len(set.ResourceRecords), test.values)
+			continue
+		}
+		for j, record := range set.ResourceRecords {
+			if *record.Value != test.values[j] {
+				t.Errorf("%s: got value %q, want %q", test.name, *record.Value, test.values[j])
+			}
+		}
+	}
+}
+
+// serve the zone by zone transfers from a name server on a free local TCP port and return its address
+func serveZone(t *testing.T, zone []string) (address string, shutdown func()) {
+	var rrs []dns.RR
+	for _, record := range zone {
+		rr, err := dns.NewRR(record)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rrs = append(rrs, rr)
+	}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	started := make(chan struct{})
+	server := &dns.Server{Listener: listener, NotifyStartedFunc: func() { close(started) }, Handler: dns.HandlerFunc(func(w dns.ResponseWriter, request *dns.Msg) {
+		response := new(dns.Msg)
+		response.SetReply(request)
+		if request.Question[0].Qtype == dns.TypeAXFR {
+			// a transfer starts and ends with the SOA record
+			response.Answer = append(append(rrs[:1:1], rrs[1:]...), rrs[0])
+		} else {
+			response.Rcode = dns.RcodeRefused
+		}
+		w.WriteMsg(response)
+	})}
+	go server.ActivateAndServe()
+	<-started
+	return listener.Addr().String(), func() { server.Shutdown() }
+}
+
+func TestRecordSets(t *testing.T) {
+	address, shutdown := serveZone(t, []string{
+		"example.com. 300 IN SOA ns1.example.com. admin.example.com. 1 3600 600 86400 300",
+		"example.com. 300 IN NS ns1.example.com.",
+		"*.example.com. 300 IN A 192.0.2.1",
+		"app.example.com. 300 IN CNAME lb.example.net.",
+		`_r53-ingress-owner.app.example.com. 300 IN TXT "heritage=route53-ingress-controller"`,
+	})
+	defer shutdown()
+	r, err := New(Config{Server: address, Zones: []string{"example.com"}, Timeout: time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		sets []string
+	}{
+		{"app.example.com", []string{"app.example.com./CNAME"}},
+		{"App.Example.com.", []string{"app.example.com./CNAME"}},
+		{"_r53-ingress-owner.app.example.com", []string{"_r53-ingress-owner.app.example.com./TXT"}},
+		{"new.example.com", nil},
+		{"*.example.com", []string{"*.example.com./A"}},
+	}
+	for _, test := range tests {
+		resourceRecordSets, err := r.RecordSets("example.com", "", test.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var sets []string
+		for _, resourceRecordSet := range resourceRecordSets {
+			sets = append(sets, *resourceRecordSet.Name+"/"+*resourceRecordSet.Type)
+		}
+		if !reflect.DeepEqual(sets, test.sets) {
+			t.Errorf("%s: got %v, want %v", test.name, sets, test.sets)
+		}
+	}
+}
@@ -0,0 +1,360 @@
+package zonefile
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	awssdk "github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/route53"
+	"github.com/dbsystel/AmazonRoute53-ingress-controller/provider"
+	"github.com/dbsystel/AmazonRoute53-ingress-controller/rfc2136"
+	"github.com/miekg/dns"
+)
+
+// prefix of the comments holding ALIAS records written by earlier versions, which are no standard record type
+const aliasComment = "; ALIAS "
+
+// Config defines the directory and the zones of the zone files
+type Config struct {
+	// directory of the zone files, named <zone>.zone
+	Directory string
+	// names of the zones which are written
+	Zones []string
+	// TTL of the SOA and NS records of new zone files
+	TTL int64
+}
+
+// ZoneFile is the provider writing the record sets to BIND zone files
+type ZoneFile struct {
+	config Config
+	mutex  sync.Mutex
+}
+
+// zone is the content of a zone file
+type zone struct {
+	rrs     []dns.RR
+	aliases []*route53.ResourceRecordSet
+}
+
+// New returns the provider for the zone files of the given config
+func New(config Config) (provider.Provider, error) {
+	if config.Directory == "" {
+		return nil, errors.New("the directory of the zone files is missing")
+	}
+	if len(config.Zones) == 0 {
+		return nil, errors.New("at least one zone for the zone files is required")
+	}
+	return &ZoneFile{config: config}, nil
+}
+
+// Zones returns the configured zones, their ID is their name
+func (z *ZoneFile) Zones(roleARN string) ([]provider.Zone, error) {
+	var zones []provider.Zone
+	for _, zone := range z.config.Zones {
+		name := strings.ToLower(strings.TrimSuffix(zone, "."))
+		zones = append(zones, provider.Zone{Name: name, ID: name})
+	}
+	return zones, nil
+}
+
+// RecordSets returns all record sets with the given name in the zone file
+func (z *ZoneFile) RecordSets(zoneID, roleARN, name string) ([]*route53.ResourceRecordSet, error) {
+	z.mutex.Lock()
+	defer z.mutex.Unlock()
+
+	content, err := z.read(zoneID)
+	if err != nil {
+		return nil, err
+	}
+	var rrs []dns.RR
+	for _, rr := range content.rrs {
+		if strings.EqualFold(rr.Header().Name, dns.Fqdn(name)) && rr.Header().Rrtype != dns.TypeSOA {
+			rrs = append(rrs, rr)
+		}
+	}
+	resourceRecordSets := rfc2136.FromRRs(rrs)
+	for _, alias := range content.aliases {
+		if strings.EqualFold(*alias.Name, dns.Fqdn(name)) {
+			resourceRecordSets = append(resourceRecordSets, alias)
+		}
+	}
+	return resourceRecordSets, nil
+}
+
+// ListRecordSets returns all record sets of the zone file
+func (z *ZoneFile) ListRecordSets(zoneID, roleARN string) ([]*route53.ResourceRecordSet, error) {
+	z.mutex.Lock()
+	defer z.mutex.Unlock()
+
+	content, err := z.read(zoneID)
+	if err != nil {
+		return nil, err
+	}
+	var rrs []dns.RR
+	for _, rr := range content.rrs {
+		if rr.Header().Rrtype != dns.TypeSOA {
+			rrs = append(rrs, rr)
+		}
+	}
+	return append(rfc2136.FromRRs(rrs), content.aliases...), nil
+}
+
+// ApplyChanges applies the changes to the zone file, bumps the serial of its SOA record and writes it.
+// The changes are applied all or nothing like a Route53 change batch.
+func (z *ZoneFile) ApplyChanges(zoneID, roleARN string, changes []*route53.Change) (string, error) {
+	z.mutex.Lock()
+	defer z.mutex.Unlock()
+
+	content, err := z.read(zoneID)
+	if err != nil {
+		return "", err
+	}
+	for _, change := range changes {
+		if err := content.apply(change); err != nil {
+			return "", err
+		}
+	}
+	serial := content.bumpSerial()
+	if err := z.write(zoneID, content); err != nil {
+		return "", err
+	}
+	return zoneID + "-" + strconv.FormatUint(uint64(serial), 10), nil
+}
+
+// ChangeStatus returns INSYNC, because the zone file is written before the change is returned
+func (z *ZoneFile) ChangeStatus(changeID, roleARN string) (string, error) {
+	return route53.ChangeStatusInsync, nil
+}
+
+// CheckConnectivity verifies that the directory of the zone files exists
+func (z *ZoneFile) CheckConnectivity() error {
+	info, err := os.Stat(z.config.Directory)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return errors.New(z.config.Directory + " is no directory")
+	}
+	return nil
+}
+
+// AliasRecords reports that ALIAS records are not supported, a name server cannot serve them from a zone file
+func (z *ZoneFile) AliasRecords() bool {
+	return false
+}
+
+// return the path of the zone file of the zone
+func (z *ZoneFile) path(zoneID string) string {
+	return filepath.Join(z.config.Directory, zoneID+".zone")
+}
+
+// read the zone file, a missing zone file is created with SOA and NS records
+func (z *ZoneFile) read(zoneID string) (*zone, error) {
+	data, err := ioutil.ReadFile(z.path(zoneID))
+	if os.IsNotExist(err) {
+		return z.newZone(zoneID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	content := &zone{}
+	parser := dns.NewZoneParser(bytes.NewReader(data), dns.Fqdn(zoneID), z.path(zoneID))
+	for rr, ok := parser.Next(); ok; rr, ok = parser.Next() {
+		content.rrs = append(content.rrs, rr)
+	}
+	if err := parser.Err(); err != nil {
+		return nil, err
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		if !strings.HasPrefix(scanner.Text(), aliasComment) {
+			continue
+		}
+		alias, err := parseAlias(strings.TrimPrefix(scanner.Text(), aliasComment))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", z.path(zoneID), err)
+		}
+		content.aliases = append(content.aliases, alias)
+	}
+	return content, scanner.Err()
+}
+
+// return the content of a new zone file with SOA and NS records
+func (z *ZoneFile) newZone(zoneID string) (*zone, error) {
+	origin := dns.Fqdn(zoneID)
+	soa, err := dns.NewRR(fmt.Sprintf("%s %d IN SOA ns.%s hostmaster.%s 0 7200 900 1209600 %d", origin, z.config.TTL, origin, origin, z.config.TTL))
+	if err != nil {
+		return nil, err
+	}
+	ns, err := dns.NewRR(fmt.Sprintf("%s %d IN NS ns.%s", origin, z.config.TTL, origin))
+	if err != nil {
+		return nil, err
+	}
+	return &zone{rrs: []dns.RR{soa, ns}}, nil
+}
+
+// write the zone file sorted by name, type and value, so unchanged records never cause a diff
+func (z *ZoneFile) write(zoneID string, content *zone) error {
+	sort.SliceStable(content.rrs, func(i, j int) bool {
+		return less(content.rrs[i], content.rrs[j])
+	})
+	sort.SliceStable(content.aliases, func(i, j int) bool {
+		return *content.aliases[i].Name+*content.aliases[i].Type < *content.aliases[j].Name+*content.aliases[j].Type
+	})
+
+	var data bytes.Buffer
+	fmt.Fprintf(&data, "; zone %s written by AmazonRoute53-ingress-controller, manual changes of records are kept, comments are lost\n", zoneID)
+	fmt.Fprintf(&data, "$ORIGIN %s\n", dns.Fqdn(zoneID))
+	for _, rr := range content.rrs {
+		fmt.Fprintln(&data, rr.String())
+	}
+	for _, alias := range content.aliases {
+		// an unknown hosted zone of the target is written as "-"
+		hostedZoneID := awssdk.StringValue(alias.AliasTarget.HostedZoneId)
+		if hostedZoneID == "" {
+			hostedZoneID = "-"
+		}
+		fmt.Fprintf(&data, "%s%s %s %s %s %t\n", aliasComment, *alias.Name, *alias.Type, *alias.AliasTarget.DNSName, hostedZoneID, awssdk.BoolValue(alias.AliasTarget.EvaluateTargetHealth))
+	}
+
+	// replace the zone file atomically, so it is never read half written
+	temporary := z.path(zoneID) + ".tmp"
+	if err := ioutil.WriteFile(temporary, data.Bytes(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(temporary, z.path(zoneID))
+}
+
+// apply the change to the content like Route53: CREATE fails for existing record sets, DELETE for missing ones
+func (c *zone) apply(change *route53.Change) error {
+	resourceRecordSet := change.ResourceRecordSet
+	name := dns.Fqdn(*resourceRecordSet.Name)
+	exists := c.exists(name, *resourceRecordSet.Type)
+	switch *change.Action {
+	case route53.ChangeActionCreate:
+		if exists {
+			return fmt.Errorf("record set %s %s exists already", name, *resourceRecordSet.Type)
+		}
+	case route53.ChangeActionDelete:
+		if !exists {
+			return fmt.Errorf("record set %s %s does not exist", name, *resourceRecordSet.Type)
+		}
+	case route53.ChangeActionUpsert:
+	default:
+		return fmt.Errorf("unsupported change action %s", *change.Action)
+	}
+	c.remove(name, *resourceRecordSet.Type)
+	if *change.Action == route53.ChangeActionDelete {
+		return nil
+	}
+
+	// ALIAS comments of earlier versions can only be deleted, new ALIAS records are rejected like by RFC2136
+	rrs, err := rfc2136.ToRRs(resourceRecordSet)
+	if err != nil {
+		return err
+	}
+	c.rrs = append(c.rrs, rrs...)
+	return nil
+}
+
+// check if a record set with the name and type exists
+func (c *zone) exists(name, recordType string) bool {
+	for _, rr := range c.rrs {
+		if strings.EqualFold(rr.Header().Name, name) && dns.TypeToString[rr.Header().Rrtype] == recordType {
+			return true
+		}
+	}
+	for _, alias := range c.aliases {
+		if strings.EqualFold(*alias.Name, name) && *alias.Type == recordType {
+			return true
+		}
+	}
+	return false
+}
+
+// remove the record set with the name and type
+func (c *zone) remove(name, recordType string) {
+	rrs := c.rrs[:0]
+	for _, rr := range c.rrs {
+		if !strings.EqualFold(rr.Header().Name, name) || dns.TypeToString[rr.Header().Rrtype] != recordType {
+			rrs = append(rrs, rr)
+		}
+	}
+	c.rrs = rrs
+	aliases := c.aliases[:0]
+	for _, alias := range c.aliases {
+		if !strings.EqualFold(*alias.Name, name) || *alias.Type != recordType {
+			aliases = append(aliases, alias)
+		}
+	}
+	c.aliases = aliases
+}
+
+// increment the serial of the SOA record and return it
+func (c *zone) bumpSerial() uint32 {
+	for _, rr := range c.rrs {
+		if soa, ok := rr.(*dns.SOA); ok {
+			soa.Serial++
+			return soa.Serial
+		}
+	}
+	return 0
+}
+
+// order resource records by name, the SOA record first, then by type and value
+func less(a, b dns.RR) bool {
+	if (a.Header().Rrtype == dns.TypeSOA) != (b.Header().Rrtype == dns.TypeSOA) {
+		return a.Header().Rrtype == dns.TypeSOA
+	}
+	if nameA, nameB := reverseName(a.Header().Name), reverseName(b.Header().Name); nameA != nameB {
+		return nameA < nameB
+	}
+	if a.Header().Rrtype != b.Header().Rrtype {
+		return a.Header().Rrtype < b.Header().Rrtype
+	}
+	return a.String() < b.String()
+}
+
+// return the labels of the name in reverse order, so subdomains follow their parent
+func reverseName(name string) string {
+	labels := dns.SplitDomainName(strings.ToLower(name))
+	for i, j := 0, len(labels)-1; i < j; i, j = i+1, j-1 {
+		labels[i], labels[j] = labels[j], labels[i]
+	}
+	return strings.Join(labels, ".")
+}
+
+// parse the ALIAS comment "<name> <type> <dns name> <hosted zone ID> <evaluate target health>"
+func parseAlias(comment string) (*route53.ResourceRecordSet, error) {
+	fields := strings.Fields(comment)
+	if len(fields) != 5 {
+		return nil, errors.New("invalid ALIAS comment: " + comment)
+	}
+	evaluateTargetHealth, err := strconv.ParseBool(fields[4])
+	if err != nil {
+		return nil, errors.New("invalid ALIAS comment: " + comment)
+	}
+	if fields[3] == "-" {
+		fields[3] = ""
+	}
+	return &route53.ResourceRecordSet{
+		Name: awssdk.String(fields[0]),
+		Type: awssdk.String(fields[1]),
+		AliasTarget: &route53.AliasTarget{
+			DNSName:              awssdk.String(fields[2]),
+			HostedZoneId:         awssdk.String(fields[3]),
+			EvaluateTargetHealth: awssdk.Bool(evaluateTargetHealth),
+		},
+	}, nil
+}
@@ -0,0 +1,246 @@
+package zonefile
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	awssdk "github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/route53"
+	"github.com/miekg/dns"
+)
+
+func newRecordSetChange(action, name, recordType, value string) *route53.Change {
+	return &route53.Change{
+		Action: awssdk.String(action),
+		ResourceRecordSet: &route53.ResourceRecordSet{
+			Name:            awssdk.String(name),
+			Type:            awssdk.String(recordType),
+			TTL:             awssdk.Int64(300),
+			ResourceRecords: []*route53.ResourceRecord{{Value: awssdk.String(value)}},
+		},
+	}
+}
+
+func newAliasChange(action, name, dnsName string) *route53.Change {
+	return &route53.Change{
+		Action: awssdk.String(action),
+		ResourceRecordSet: &route53.ResourceRecordSet{
+			Name:        awssdk.String(name),
+			Type:        awssdk.String("A"),
+			AliasTarget: &route53.AliasTarget{DNSName: awssdk.String(dnsName), HostedZoneId: awssdk.String(""), EvaluateTargetHealth: awssdk.Bool(false)},
+		},
+	}
+}
+
+func TestApply(t *testing.T) {
+	tests := []struct {
+		name    string
+		changes []*route53.Change
+		records []string
+		aliases []string
+		failed  bool
+	}{
+		{
+			name:    "create",
+			changes: []*route53.Change{newRecordSetChange("CREATE", "new.example.com", "CNAME", "lb.example.net.")},
+			records: []string{"app.example.com./CNAME", "new.example.com./CNAME"},
+			aliases: []string{"alias.example.com."},
+		},
+		{
+			name:    "create of an existing record set",
+			changes: []*route53.Change{newRecordSetChange("CREATE", "App.example.com", "CNAME", "lb.example.net.")},
+			failed:  true,
+		},
+		{
+			name:    "upsert replaces the record set",
+			changes: []*route53.Change{newRecordSetChange("UPSERT", "app.example.com", "CNAME", "other.example.net.")},
+			records: []string{"app.example.com./CNAME"},
+			aliases: []string{"alias.example.com."},
+		},
+		{
+			name:    "upsert of an alias",
+			changes: []*route53.Change{newAliasChange("UPSERT", "app2.example.com", "lb.example.net")},
+			failed:  true,
+		},
+		{
+			name:    "delete",
+			changes: []*route53.Change{newRecordSetChange("DELETE", "app.example.com", "CNAME", "lb.example.net."), newAliasChange("DELETE", "alias.example.com", "lb.example.net")},
+		},
+		{
+			name:    "delete of a missing record set",
+			changes: []*route53.Change{newRecordSetChange("DELETE", "missing.example.com", "CNAME", "lb.example.net.")},
+			failed:  true,
+		},
+		{
+			name:    "unsupported action",
+			changes: []*route53.Change{newRecordSetChange("MOVE", "app.example.com", "CNAME", "lb.example.net.")},
+			failed:  true,
+		},
+	}
+	for _, test := range tests {
+		rr, err := dns.NewRR("app.example.com. 300 IN CNAME lb.example.net.")
+		if err != nil {
+			t.Fatal(err)
+		}
+		// the ALIAS comment of an earlier version
+		alias, err := parseAlias("alias.example.com. A lb.example.net - false")
+		if err != nil {
+			t.Fatal(err)
+		}
+		content := &zone{rrs: []dns.RR{rr}, aliases: []*route53.ResourceRecordSet{alias}}
+
+		var failed bool
+		for _, change := range test.changes {
+			if err := content.apply(change); err != nil {
+				failed = true
+			}
+		}
+		if failed != test.failed {
+			t.Errorf("%s: got failure %v, want %v", test.name, failed, test.failed)
+			continue
+		}
+		if test.failed {
+			continue
+		}
+		var records, aliases []string
+		for _, rr := range content.rrs {
+			records = append(records, strings.ToLower(rr.Header().Name)+"/"+dns.TypeToString[rr.Header().Rrtype])
+		}
+		for _, alias := range content.aliases {
+			aliases = append(aliases, *alias.Name)
+		}
+		sort.Strings(records)
+		sort.Strings(aliases)
+		if strings.Join(records, ",") != strings.Join(test.records, ",") || strings.Join(aliases, ",") != strings.Join(test.aliases, ",") {
+			t.Errorf("%s: got records %v and aliases %v, want %v and %v", test.name, records, aliases, test.records, test.aliases)
+		}
+	}
+}
+
+func TestLess(t *testing.T) {
+	var rrs []dns.RR
+	for _, record := range []string{
+		"www.app.example.com. 300 IN CNAME app.example.com.",
+		"b.example.com. 300 IN A 192.0.2.2",
+		"app.example.com. 300 IN TXT \"owner\"",
+		"example.com. 300 IN NS ns.example.com.",
+		"app.example.com. 300 IN A 192.0.2.9",
+		"app.example.com. 300 IN A 192.0.2.1",
+		"example.com. 300 IN SOA ns.example.com. hostmaster.example.com. 1 7200 900 1209600 300",
+	} {
+		rr, err := dns.NewRR(record)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rrs = append(rrs, rr)
+	}
+	sort.SliceStable(rrs, func(i, j int) bool {
+		return less(rrs[i], rrs[j])
+	})
+
+	want := []string{
+		"example.com./SOA",
+		"example.com./NS",
+		"app.example.com./A/192.0.2.1",
+		"app.example.com./A/192.0.2.9",
+		"app.example.com./TXT",
+		"www.app.example.com./CNAME",
+		"b.example.com./A/192.0.2.2",
+	}
+	for i, rr := range rrs {
+		got := rr.Header().Name + "/" + dns.TypeToString[rr.Header().Rrtype]
+		if a, ok := rr.(*dns.A); ok {
+			got += "/" + a.A.String()
+		}
+		if got != want[i] {
+			t.Errorf("record %d: got %s, want %s", i, got, want[i])
+		}
+	}
+}
+
+func TestParseAlias(t *testing.T) {
+	tests := []struct {
+		comment      string
+		dnsName      string
+		hostedZoneID string
+		evaluate     bool
+		failed       bool
+	}{
+		{"app.example.com. A lb.example.net Z215JYRZR1TBD5 true", "lb.example.net", "Z215JYRZR1TBD5", true, false},
+		{"app.example.com. A lb.example.net - false", "lb.example.net", "", false, false},
+		{"app.example.com. A lb.example.net -", "", "", false, true},
+		{"app.example.com. A lb.example.net - maybe", "", "", false, true},
+	}
+	for _, test := range tests {
+		alias, err := parseAlias(test.comment)
+		if (err != nil) != test.failed {
+			t.Errorf("%q: got error %v, want failure %v", test.comment, err, test.failed)
+			continue
+		}
+		if test.failed {
+			continue
+		}
+		target := alias.AliasTarget
+		if *target.DNSName != test.dnsName || *target.HostedZoneId != test.hostedZoneID || *target.EvaluateTargetHealth != test.evaluate {
+			t.Errorf("%q: got %s %q %v, want %s %q %v", test.comment, *target.DNSName, *target.HostedZoneId, *target.EvaluateTargetHealth, test.dnsName, test.hostedZoneID, test.evaluate)
+		}
+	}
+}
+
+func TestWriteAndRead(t *testing.T) {
+	directory, err := ioutil.TempDir("", "zonefile")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(directory)
+
+	provider, err := New(Config{Directory: directory, Zones: []string{"example.com"}, TTL: 300})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := provider.ApplyChanges("example.com", "", []*route53.Change{newRecordSetChange("CREATE", "app.example.com", "CNAME", "lb.example.net.")}); err != nil {
+		t.Fatal(err)
+	}
+	// a failing change batch leaves the zone file unchanged
+	if _, err := provider.ApplyChanges("example.com", "", []*route53.Change{newRecordSetChange("DELETE", "missing.example.com", "CNAME", "lb.example.net.")}); err == nil {
+		t.Error("expected an error for the deletion of a missing record set")
+	}
+	if _, err := provider.ApplyChanges("example.com", "", []*route53.Change{newAliasChange("CREATE", "new.example.com", "lb.example.net")}); err == nil {
+		t.Error("expected an error for the creation of an ALIAS record")
+	}
+
+	// ALIAS comments written by earlier versions are read back and kept
+	path := filepath.Join(directory, "example.com.zone")
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(path, append(data, "; ALIAS alias.example.com. A lb.example.net - false\n"...), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := provider.ApplyChanges("example.com", "", []*route53.Change{newRecordSetChange("CREATE", "other.example.com", "CNAME", "lb.example.net.")}); err != nil {
+		t.Fatal(err)
+	}
+	if data, err = ioutil.ReadFile(path); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "; ALIAS alias.example.com. A lb.example.net - false\n") {
+		t.Errorf("ALIAS comment missing in:\n%s", data)
+	}
+
+	recordSets, err := provider.ListRecordSets("example.com", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, recordSet := range recordSets {
+		names = append(names, *recordSet.Name+"/"+*recordSet.Type)
+	}
+	if got, want := strings.Join(names, ","), "example.com./NS,app.example.com./CNAME,other.example.com./CNAME,alias.example.com./A"; got != want {
+		t.Errorf("got record sets %s, want %s", got, want)
+	}
+}